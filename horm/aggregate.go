@@ -0,0 +1,277 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/types"
+)
+
+// 聚合查询结果别名
+const (
+	aggAlias = "horm_agg"
+	aggSum   = "sum"
+	aggAvg   = "avg"
+	aggMin   = "min"
+	aggMax   = "max"
+	aggCount = "count"
+)
+
+// isElastic 执行单元对应的数据库是否为 elastic，根据配置文件中的数据库类型判断
+func (s *Query) isElastic() (bool, error) {
+	typ, err := s.dbType()
+	return typ == dbTypeElastic, err
+}
+
+// aggQuery 聚合查询语句，复制执行单元并清空列、分页、偏移与排序，不修改原语句。
+// 分组查询的聚合结果有多行，只取一行会得到第一个分组的值，因此不支持 Group、Having，分组聚合请使用 Column 指定聚合函数
+func (s *Query) aggQuery() (*Query, error) {
+	if len(s.Unit.Group) > 0 || len(s.Unit.Having) > 0 {
+		return nil, errs.New(errs.ErrReqParamInvalid,
+			"aggregate does not support group by or having, use Group with Column(\"count(1)\") and FindAll instead")
+	}
+
+	q := s.clone()
+	q.Unit.Column, q.Unit.Order = nil, nil
+	q.Unit.Page, q.Unit.Size, q.Unit.From = 0, -1, 0
+	return q, nil
+}
+
+// Count 统计满足条件的记录数，mysql、postgresql、clickhouse 为 count(1)，elastic 为命中总数 total，
+// 忽略语句中的列、分页与排序，不会修改原语句
+func (s *Query) Count(ctx context.Context) (int64, error) {
+	if s.Error != nil {
+		return 0, s.Error
+	}
+
+	es, err := s.isElastic()
+	if err != nil {
+		return 0, err
+	}
+
+	q, err := s.aggQuery()
+	if err != nil {
+		return 0, err
+	}
+
+	if es {
+		detail, err := q.esDetail(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		return int64(detail.Total), nil
+	}
+
+	ret, err := q.sqlAgg(ctx, aggCount, "1")
+	if err != nil || ret == nil {
+		return 0, err
+	}
+
+	n, err := types.InterfaceToInt64(ret)
+	if err != nil {
+		return 0, errs.Newf(errs.ErrClientDecode, "[request_id=%d] count result decode error: %v", q.RequestID, err)
+	}
+
+	return n, nil
+}
+
+// Sum 求和，没有满足条件的记录时返回 0
+func (s *Query) Sum(ctx context.Context, column string) (float64, error) {
+	return s.aggFloat(ctx, aggSum, column)
+}
+
+// Avg 求平均值，没有满足条件的记录时返回 0
+func (s *Query) Avg(ctx context.Context, column string) (float64, error) {
+	return s.aggFloat(ctx, aggAvg, column)
+}
+
+// Min 求最小值并解码到 dest（指针），列可以是数值、时间、字符串等类型，没有满足条件的记录时 isNil=true
+func (s *Query) Min(ctx context.Context, column string, dest interface{}) (isNil bool, err error) {
+	return s.aggDecode(ctx, aggMin, column, dest)
+}
+
+// Max 求最大值并解码到 dest（指针），列可以是数值、时间、字符串等类型，没有满足条件的记录时 isNil=true
+func (s *Query) Max(ctx context.Context, column string, dest interface{}) (isNil bool, err error) {
+	return s.aggDecode(ctx, aggMax, column, dest)
+}
+
+// Distinct 去重查询，mysql、postgresql、clickhouse 为 SELECT DISTINCT，elastic 为 collapse（仅支持单个字段）
+func (s *Query) Distinct(columns ...string) *Query {
+	if len(columns) == 0 {
+		s.Error = errs.New(errs.ErrReqParamInvalid, "distinct columns is empty")
+		return s
+	}
+
+	es, err := s.isElastic()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	if es {
+		if len(columns) > 1 {
+			s.Error = errs.New(errs.ErrReqParamInvalid, "elastic distinct only support one field")
+			return s
+		}
+		return s.Collapse(columns[0]).Column(columns[0])
+	}
+
+	distinct := append([]string{"DISTINCT " + columns[0]}, columns[1:]...)
+	return s.Column(distinct...)
+}
+
+// Pluck 查询单列的值到 dest 切片指针中，例如 var ids []int64; q.Pluck(ctx, "id", &ids)，不会修改原语句
+func (s *Query) Pluck(ctx context.Context, column string, dest interface{}) error {
+	if s.Error != nil {
+		return s.Error
+	}
+
+	q := s.clone()
+	if q.Unit.Op == "" {
+		q.FindAll()
+	}
+
+	q.Column(column)
+
+	var rows []map[string]interface{}
+	isNil, err := q.Exec(ctx, &rows)
+	if err != nil || isNil {
+		return err
+	}
+
+	values := make([]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row[column]
+	}
+
+	err = q.GetCoder().Decode(q.ResultType, values, []interface{}{dest})
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode, "[request_id=%d] pluck result decode error: %v", q.RequestID, err)
+	}
+
+	return nil
+}
+
+// agg 在语句副本上执行聚合查询，返回聚合结果原值，没有满足条件的记录时为 nil
+func (s *Query) agg(ctx context.Context, fn, column string) (*Query, interface{}, error) {
+	if s.Error != nil {
+		return s, nil, s.Error
+	}
+
+	es, err := s.isElastic()
+	if err != nil {
+		return s, nil, err
+	}
+
+	q, err := s.aggQuery()
+	if err != nil {
+		return s, nil, err
+	}
+
+	var ret interface{}
+	if es {
+		ret, err = q.esAgg(ctx, fn, column)
+	} else {
+		ret, err = q.sqlAgg(ctx, fn, column)
+	}
+
+	return q, ret, err
+}
+
+// aggFloat 数值聚合查询
+func (s *Query) aggFloat(ctx context.Context, fn, column string) (float64, error) {
+	q, ret, err := s.agg(ctx, fn, column)
+	if err != nil || ret == nil {
+		return 0, err
+	}
+
+	f, err := types.InterfaceToFloat64(ret)
+	if err != nil {
+		return 0, errs.Newf(errs.ErrClientDecode, "[request_id=%d] %s result decode error: %v", q.RequestID, fn, err)
+	}
+
+	return f, nil
+}
+
+// aggDecode 聚合查询结果解码到 dest
+func (s *Query) aggDecode(ctx context.Context, fn, column string, dest interface{}) (bool, error) {
+	q, ret, err := s.agg(ctx, fn, column)
+	if err != nil {
+		return false, err
+	}
+
+	if ret == nil {
+		return true, nil
+	}
+
+	err = q.GetCoder().Decode(q.ResultType, ret, []interface{}{dest})
+	if err != nil {
+		return false, errs.Newf(errs.ErrClientDecode, "[request_id=%d] %s result decode error: %v", q.RequestID, fn, err)
+	}
+
+	return false, nil
+}
+
+// sqlAgg mysql、postgresql、clickhouse 聚合查询，例如 SELECT sum(score) AS horm_agg FROM student WHERE ...
+func (s *Query) sqlAgg(ctx context.Context, fn, column string) (interface{}, error) {
+	s.Find().Column(fn + "(" + column + ") AS " + aggAlias)
+
+	ret := map[string]interface{}{}
+	isNil, err := s.Exec(ctx, &ret)
+	if err != nil || isNil {
+		return nil, err
+	}
+
+	return ret[aggAlias], nil
+}
+
+// esAgg elastic 聚合查询，聚合结果由统一接入服务在 detail.extras.aggregations 中返回
+func (s *Query) esAgg(ctx context.Context, fn, column string) (interface{}, error) {
+	aggs := map[string]interface{}{
+		aggAlias: map[string]interface{}{fn: map[string]interface{}{"field": column}},
+	}
+
+	detail, err := s.esDetail(ctx, aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregations, _ := types.InterfaceToMap(detail.Extras["aggregations"])
+	agg, _ := types.InterfaceToMap(aggregations[aggAlias])
+	return agg["value"], nil
+}
+
+// esDetail elastic 分页查询 1 条数据，获取命中总数与聚合结果等查询细节
+func (s *Query) esDetail(ctx context.Context, aggs map[string]interface{}) (*proto.Detail, error) {
+	s.FindAll().Page(1, 1).SetParam("track_total_hits", true)
+	if aggs != nil {
+		s.SetParam("aggs", aggs)
+	}
+
+	result := proto.PageResult{}
+	_, err := s.Exec(ctx, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Detail == nil {
+		return &proto.Detail{}, nil
+	}
+
+	return result.Detail, nil
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+// unitHandler 记录请求的执行单元，并返回 resp
+func unitHandler(unit **proto.Unit, resp string) func(*proto.RequestHeader, []byte) (*proto.ResponseHeader, []byte, error) {
+	return func(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+		var units []*proto.Unit
+		if err := json.Unmarshal(body, &units); err != nil {
+			return nil, nil, err
+		}
+		*unit = units[0]
+		return &proto.ResponseHeader{RequestId: head.RequestId, QueryMode: head.QueryMode}, []byte(resp), nil
+	}
+}
+
+func TestAggregateQueryCopy(t *testing.T) {
+	tests := []struct {
+		name   string
+		table  string
+		resp   string
+		agg    func(ctx context.Context, q *Query) (interface{}, error)
+		want   interface{}
+		column []string
+		params bool
+	}{
+		{"mysql count", "mysql_test", `{"horm_agg":7}`, func(ctx context.Context, q *Query) (interface{}, error) {
+			return q.Count(ctx)
+		}, int64(7), []string{"count(1) AS horm_agg"}, false},
+		{"mysql sum", "mysql_test", `{"horm_agg":12.5}`, func(ctx context.Context, q *Query) (interface{}, error) {
+			return q.Sum(ctx, "score")
+		}, 12.5, []string{"sum(score) AS horm_agg"}, false},
+		{"mysql max string", "mysql_test", `{"horm_agg":"2024-06-12"}`, func(ctx context.Context, q *Query) (interface{}, error) {
+			var day string
+			_, err := q.Max(ctx, "birthday", &day)
+			return day, err
+		}, "2024-06-12", []string{"max(birthday) AS horm_agg"}, false},
+		{"elastic count", "es_test", `{"detail":{"total":9}}`, func(ctx context.Context, q *Query) (interface{}, error) {
+			return q.Count(ctx)
+		}, int64(9), nil, true},
+		{"elastic min", "es_test", `{"detail":{"total":9,"extras":{"aggregations":{"horm_agg":{"value":3}}}}}`,
+			func(ctx context.Context, q *Query) (interface{}, error) {
+				var min int
+				_, err := q.Min(ctx, "age", &min)
+				return min, err
+			}, 3, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var unit *proto.Unit
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, tt.resp))))
+
+			q := NewQuery(tt.table).FindAll(Where{"age >": 10}).Column("id", "name").
+				Order("-id").Page(3, 20).WithClient(c)
+			origin := *q.Unit
+
+			got, err := tt.agg(context.Background(), q)
+			if err != nil {
+				t.Fatalf("aggregate error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("result %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(*q.Unit, origin) || q.Unit.Params != nil {
+				t.Fatalf("caller query modified: %+v", q.Unit)
+			}
+
+			if !reflect.DeepEqual(unit.Column, tt.column) || len(unit.Order) != 0 || unit.From != 0 {
+				t.Fatalf("aggregate unit has column %v order %v from %d", unit.Column, unit.Order, unit.From)
+			}
+
+			if tt.params { // elastic 查询 1 条获取命中总数
+				if unit.Page != 1 || unit.Size != 1 || unit.Params["track_total_hits"] != true {
+					t.Fatalf("elastic aggregate unit page %d size %d params %v", unit.Page, unit.Size, unit.Params)
+				}
+			} else if unit.Page != 0 || unit.Size > 0 || unit.Op != "find" {
+				t.Fatalf("sql aggregate unit op %s page %d size %d", unit.Op, unit.Page, unit.Size)
+			}
+
+			if unit.Where["age >"] != float64(10) {
+				t.Fatalf("aggregate unit where %v", unit.Where)
+			}
+		})
+	}
+}
+
+func TestAggregateNil(t *testing.T) {
+	var calls int32
+	var unit *proto.Unit
+	c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, `{"horm_agg":null}`))))
+
+	var max int
+	isNil, err := NewQuery("mysql_test").WithClient(c).Max(context.Background(), "age", &max)
+	if err != nil || !isNil {
+		t.Fatalf("max isNil %v error %v, want isNil", isNil, err)
+	}
+}
+
+func TestAggregateUnknownDBType(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{"not configured", "student"},
+		{"not configured alias", "student(s1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler)))
+
+			_, err := NewQuery(tt.table).WithClient(c).Count(context.Background())
+			if errs.Code(err) != errs.ErrDBConfigNotFound {
+				t.Fatalf("count error %v, want db config not found", err)
+			}
+
+			if q := NewQuery(tt.table).Distinct("name"); errs.Code(q.Error) != errs.ErrDBConfigNotFound {
+				t.Fatalf("distinct error %v, want db config not found", q.Error)
+			}
+
+			if calls != 0 {
+				t.Fatalf("requests %d, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestAggregateGroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+	}{
+		{"group", NewQuery("mysql_test").FindAll().Group("class")},
+		{"having", NewQuery("mysql_test").FindAll().Having(Where{"score >": 60})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler)))
+			q := tt.query.WithClient(c)
+
+			if _, err := q.Count(context.Background()); errs.Code(err) != errs.ErrReqParamInvalid {
+				t.Fatalf("count error %v, want group rejected", err)
+			}
+
+			if _, err := q.Sum(context.Background(), "score"); errs.Code(err) != errs.ErrReqParamInvalid {
+				t.Fatalf("sum error %v, want group rejected", err)
+			}
+
+			if calls != 0 {
+				t.Fatalf("requests %d, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestQueryClone(t *testing.T) {
+	q := NewQuery("mysql_test").Update(Map{"age": 1}, Where{"id": 1}).Extend("k", "v").
+		SetParam("p", 1).Group("class").Having(Where{"n >": 1})
+
+	c := q.clone()
+	c.Unit.Where["id"] = 2
+	c.Unit.Data["age"] = 2
+	c.Unit.Params["p"] = 2
+	c.Extend("k", "v2")
+	c.Extend(unitTimeoutKey, 100)
+	c.Unit.Having["n >"] = 2
+	c.Unit.Group[0] = "grade"
+
+	if q.Unit.Where["id"] != 1 || q.Unit.Data["age"] != 1 ||
+		q.Unit.Params["p"] != 1 || len(q.Unit.Extend) != 1 || q.Unit.Extend["k"] != "v" ||
+		q.Unit.Having["n >"] != 1 || q.Unit.Group[0] != "class" {
+		t.Fatalf("original query modified by clone: %+v", q.Unit)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/util"
+)
+
+// 默认批量参数
+const (
+	defaultBatchWait  = 500 * time.Microsecond
+	defaultBatchUnits = 32
+)
+
+// batchCli 批量查询客户端，将并发的单执行单元 Exec 合并为一次并行查询（QueryModeParallel）请求
+type batchCli struct {
+	*cli
+	maxWait  time.Duration
+	maxUnits int
+
+	mu      sync.Mutex
+	pending []*batchItem
+	timer   *time.Timer
+}
+
+// batchItem 等待批量发送的 Exec 调用，批量请求只写入 batchItem 自身的字段，
+// 结果解码到私有接收者，调用方等到结果后再拷贝到自己的接收者，放弃等待的调用方不会再被写入。
+type batchItem struct {
+	ctx       context.Context
+	q         *Query
+	receivers []interface{} // 私有接收者
+	exists    bool          // exists、hexists 未传接收者时的结果
+	isNil     bool
+	err       error
+	done      chan struct{}
+}
+
+// NewBatchClient 创建批量查询客户端，并发的单执行单元 Exec 调用会被收集起来，最多等待 maxWait 或凑够 maxUnits 个执行单元后，
+// 以唯一别名作为一次并行查询发送，每个执行单元的结果、是否为空与错误分别返回给各自的调用方。
+// 包含子查询、并行查询、事务，或设置了调用参数、超时、压缩、缓存、request_id 等独立请求信息的查询不参与批量，直接发送。
+// param: name 配置名
+// param: maxWait 最大等待时间，小于等于 0 时默认 500 微秒
+// param: maxUnits 单次批量最大执行单元数，小于等于 0 时默认 32
+// param: opts 参数配置
+func NewBatchClient(name string, maxWait time.Duration, maxUnits int, opts ...Option) Client {
+	if maxWait <= 0 {
+		maxWait = defaultBatchWait
+	}
+
+	if maxUnits <= 0 {
+		maxUnits = defaultBatchUnits
+	}
+
+	return &batchCli{
+		cli:      NewClient(name, opts...).(*cli),
+		maxWait:  maxWait,
+		maxUnits: maxUnits,
+	}
+}
+
+// Exec 单执行单元，可批量的查询会与其他并发调用合并发送
+func (b *batchCli) Exec(ctx context.Context, q *Query, retReceiver ...interface{}) (bool, error) {
+	if !batchable(q) {
+		return b.cli.Exec(ctx, q, retReceiver...)
+	}
+
+	item := &batchItem{ctx: ctx, q: q, receivers: privateReceivers(retReceiver), done: make(chan struct{})}
+	b.add(item)
+
+	select {
+	case <-item.done:
+	case <-ctx.Done():
+		b.remove(item)
+		return false, wrapError(ctxError(ctx, "batched request"), q.Unit.Name, q)
+	}
+
+	if item.err != nil {
+		return false, item.err
+	}
+
+	if item.isNil {
+		return true, nil
+	}
+
+	if len(retReceiver) == 0 && (q.Unit.Op == consts.OpExists || q.Unit.Op == consts.OpHExists) {
+		return !item.exists, nil
+	}
+
+	copyReceivers(retReceiver, item.receivers)
+	return false, nil
+}
+
+// privateReceivers 为每个指针接收者创建同类型的私有接收者，非指针接收者原样使用
+func privateReceivers(receivers []interface{}) []interface{} {
+	ret := make([]interface{}, len(receivers))
+	for i, r := range receivers {
+		if isPtrReceiver(r) {
+			ret[i] = reflect.New(reflect.TypeOf(r).Elem()).Interface()
+		} else {
+			ret[i] = r
+		}
+	}
+	return ret
+}
+
+// copyReceivers 将私有接收者的结果拷贝到调用方的接收者
+func copyReceivers(receivers, private []interface{}) {
+	for i, r := range receivers {
+		if isPtrReceiver(r) {
+			reflect.ValueOf(r).Elem().Set(reflect.ValueOf(private[i]).Elem())
+		}
+	}
+}
+
+func isPtrReceiver(r interface{}) bool {
+	rv := reflect.ValueOf(r)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil()
+}
+
+// add 加入等待队列，凑够 maxUnits 个执行单元立即发送，否则最多等待 maxWait
+func (b *batchCli) add(item *batchItem) {
+	b.mu.Lock()
+	b.pending = append(b.pending, item)
+
+	if len(b.pending) >= b.maxUnits {
+		items := b.take()
+		b.mu.Unlock()
+		go b.flush(items)
+		return
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.maxWait, func() {
+			b.mu.Lock()
+			items := b.take()
+			b.mu.Unlock()
+			b.flush(items)
+		})
+	}
+
+	b.mu.Unlock()
+}
+
+// remove 调用方放弃等待时，从等待队列中移除尚未发送的调用
+func (b *batchCli) remove(item *batchItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, p := range b.pending {
+		if p == item {
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			break
+		}
+	}
+
+	if len(b.pending) == 0 && b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// take 取出所有等待中的调用，调用方需持有锁
+func (b *batchCli) take() []*batchItem {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	items := b.pending
+	b.pending = nil
+	return items
+}
+
+// flush 以唯一别名构造并行查询并发送
+func (b *batchCli) flush(items []*batchItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	var head, last *Query
+	aliases := make([]string, len(items))
+	for i, item := range items {
+		unit := *item.q.Unit
+		name, _ := util.Alias(unit.Name)
+
+		aliases[i] = fmt.Sprintf("%s(horm_batch_%d)", name, i)
+		s := NewQuery(aliases[i])
+		unit.Name = s.Unit.Name
+		s.Unit = &unit
+		s.ResultType = item.q.ResultType
+		s.Coder = item.q.Coder
+
+		receivers := item.receivers
+		if len(receivers) == 0 && (unit.Op == consts.OpExists || unit.Op == consts.OpHExists) {
+			receivers = []interface{}{&item.exists}
+		}
+		s.WithReceiver(&item.isNil, &item.err, receivers...)
+
+		if head == nil {
+			head = s
+		} else {
+			s.first = head
+			s.last = last
+			last.next = s
+		}
+		last = s
+	}
+
+	ctx, cancel := batchContext(items)
+	defer cancel()
+
+	err := b.cli.PExec(ctx, head)
+
+	var me *MultiError
+	unitErrs := errors.As(err, &me) // strict 模式下执行单元错误已分别返回给各调用方
+
+	for i, item := range items {
+		if item.err == nil {
+			if !unitErrs {
+				item.err = err
+			}
+			continue
+		}
+
+		// 执行单元错误中的批量别名还原为调用方的执行单元名
+		var qe *QueryError
+		if errors.As(item.err, &qe) && qe.Unit == aliases[i] {
+			qe.Unit = item.q.Unit.Name
+		}
+	}
+
+	for _, item := range items {
+		close(item.done)
+	}
+}
+
+// batchable 是否可以参与批量
+func batchable(q *Query) bool {
+	return q.next == nil && q.sub == nil && q.trans == nil && q.parent == nil && q.Error == nil &&
+		q.Unit.Op != consts.OpTransaction && len(q.CallOptions) == 0 && q.Timeout == 0 &&
+		q.RespInfo == nil && q.CacheTTL == 0 && !q.Compress && q.CompressType == 0 && q.RequestID == 0 && q.TraceID == "" &&
+		q.Unit.Params[paramLock] == nil && q.Unit.Params[paramLockWait] == nil
+}
+
+// batchContext 批量请求 context，携带第一个调用方 context 的值（例如 trace），不随单个调用方取消。
+// 超时时间取所有调用方中最长的，有调用方未设置超时时间时不设置，单个调用方的超时由其自身的 ctx 控制等待。
+func batchContext(items []*batchItem) (context.Context, context.CancelFunc) {
+	var deadline time.Time
+	for _, item := range items {
+		d, ok := item.ctx.Deadline()
+		if !ok {
+			deadline = time.Time{}
+			break
+		}
+
+		if d.After(deadline) {
+			deadline = d
+		}
+	}
+
+	ctx := context.Context(valueContext{items[0].ctx})
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline)
+}
+
+// valueContext 仅继承父 context 的值，不继承超时与取消
+type valueContext struct {
+	parent context.Context
+}
+
+func (valueContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (valueContext) Done() <-chan struct{}               { return nil }
+func (valueContext) Err() error                          { return nil }
+func (c valueContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
@@ -0,0 +1,201 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/util"
+)
+
+// batchHandler 按请求中的执行单元别名返回 {"id": i}，failed 中的执行单元返回数据库错误
+func batchHandler(release <-chan struct{}, failed map[string]bool) func(head *proto.RequestHeader,
+	body []byte) (*proto.ResponseHeader, []byte, error) {
+	return func(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+		if release != nil {
+			<-release
+		}
+
+		var units []*proto.Unit
+		if err := json.Api.Unmarshal(body, &units); err != nil {
+			return nil, nil, err
+		}
+
+		header := &proto.ResponseHeader{RequestId: head.RequestId, QueryMode: head.QueryMode,
+			RspErrs: map[string]*proto.Error{}}
+		data := map[string]interface{}{}
+
+		for i, unit := range units {
+			_, alias := util.Alias(unit.Name)
+			if failed[alias] {
+				header.RspErrs[alias] = &proto.Error{Type: int32(errs.ETypeDatabase), Code: 1062, Msg: "unit failed"}
+				continue
+			}
+			data[alias] = map[string]interface{}{"id": i}
+		}
+
+		result, err := json.Api.Marshal(data)
+		return header, result, err
+	}
+}
+
+func TestBatchCallerCanceledBeforeFlush(t *testing.T) {
+	var calls int32
+	b := NewBatchClient("ws_test.app1.server1.service1", time.Hour, 32,
+		WithInterceptor(mockInterceptor(&calls, batchHandler(nil, nil)))).(*batchCli)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var ret map[string]interface{}
+	_, err := b.Exec(ctx, NewQuery("student").Find(Where{"id": 1}), &ret)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want timeout", err)
+	}
+
+	b.mu.Lock()
+	pending, timer := len(b.pending), b.timer
+	b.mu.Unlock()
+
+	if pending != 0 || timer != nil {
+		t.Fatalf("canceled call is still pending")
+	}
+
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Fatalf("canceled call is sent")
+	}
+}
+
+func TestBatchCallerCanceledInFlight(t *testing.T) {
+	release := make(chan struct{})
+
+	var calls int32
+	b := NewBatchClient("ws_test.app1.server1.service1", 5*time.Millisecond, 2,
+		WithInterceptor(mockInterceptor(&calls, batchHandler(release, nil)))).(*batchCli)
+
+	shortCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var shortRet, longRet map[string]interface{}
+	var shortErr, longErr error
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, shortErr = b.Exec(shortCtx, NewQuery("student").Find(Where{"id": 1}), &shortRet)
+	}()
+	go func() {
+		defer wg.Done()
+		_, longErr = b.Exec(context.Background(), NewQuery("student").Find(Where{"id": 2}), &longRet)
+	}()
+
+	// 短超时的调用方返回后再放行批量请求
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if !errors.Is(shortErr, ErrTimeout) {
+		t.Fatalf("short deadline caller got %v, want timeout", shortErr)
+	}
+
+	if shortRet != nil {
+		t.Fatalf("receiver of canceled caller is written: %v", shortRet)
+	}
+
+	if longErr != nil || longRet == nil {
+		t.Fatalf("long deadline caller got %v, %v, want result", longRet, longErr)
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("requests %d, want 1 batched request", calls)
+	}
+}
+
+func TestBatchUnitErrorName(t *testing.T) {
+	var calls int32
+	b := NewBatchClient("ws_test.app1.server1.service1", 5*time.Millisecond, 2,
+		WithInterceptor(mockInterceptor(&calls, batchHandler(nil, map[string]bool{"horm_batch_0": true,
+			"horm_batch_1": true})))).(*batchCli)
+
+	var wg sync.WaitGroup
+	got := make([]error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var ret map[string]interface{}
+			_, got[i] = b.Exec(context.Background(), NewQuery(fmt.Sprintf("student(s%d)", i)).Find(Where{"id": i}), &ret)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range got {
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Fatalf("got %v, want unit error", err)
+		}
+
+		if want := fmt.Sprintf("student(s%d)", i); qe.Unit != want {
+			t.Fatalf("unit error name %s, want %s", qe.Unit, want)
+		}
+	}
+}
+
+func TestBatchContextDeadline(t *testing.T) {
+	now := time.Now()
+
+	withDeadline := func(d time.Duration) context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), now.Add(d))
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	tests := []struct {
+		name     string
+		ctxs     []context.Context
+		deadline time.Time
+	}{
+		{"longest", []context.Context{withDeadline(time.Second), withDeadline(3 * time.Second),
+			withDeadline(2 * time.Second)}, now.Add(3 * time.Second)},
+		{"no deadline", []context.Context{withDeadline(time.Second), context.Background()}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]*batchItem, len(tt.ctxs))
+			for i, ctx := range tt.ctxs {
+				items[i] = &batchItem{ctx: ctx}
+			}
+
+			ctx, cancel := batchContext(items)
+			defer cancel()
+
+			deadline, _ := ctx.Deadline()
+			if !deadline.Equal(tt.deadline) {
+				t.Fatalf("deadline %v, want %v", deadline, tt.deadline)
+			}
+		})
+	}
+}
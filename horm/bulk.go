@@ -0,0 +1,318 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/types"
+	"github.com/horm-database/go-horm/horm/codec"
+)
+
+// 默认批量插入参数
+const (
+	defaultBulkChunkRows  = 500
+	defaultBulkChunkBytes = 4 << 20
+)
+
+// BulkOptions 批量插入参数
+type BulkOptions struct {
+	ChunkRows    int           // 每批最大行数，小于等于 0 时默认 500
+	ChunkBytes   int           // 每批数据编码后的最大字节数，小于等于 0 时默认 4MB，单行超过该大小时单独成批
+	Concurrency  int           // 并发批数，小于等于 0 时为 1
+	Retries      int           // 失败批次的最大重试次数，仅重试超时、网络错误等可重试错误，见 IsRetryable
+	RetryBackoff time.Duration // 重试间隔
+	RetryTimeout bool          // insert 是否重试客户端超时、网络错误，这类批次可能已经写入，重试可能重复插入，replace 幂等总会重试
+	Replace      bool          // 使用 replace 替代 insert
+	Client       Client        // 执行客户端，为 nil 时使用查询默认客户端
+	Coder        codec.Codec   // 编解码器，为 nil 时使用默认编解码器
+}
+
+// BulkChunk 单批插入结果
+type BulkChunk struct {
+	Offset   int             // 本批第一行在 rows 中的下标
+	Rows     int             // 本批行数
+	Attempts int             // 请求次数，包括重试
+	Err      error           // 本批错误
+	Results  []*proto.ModRet // elastic 等支持部分成功的数据库返回的每行插入结果，可通过 IsAllSuccess 判断是否全部成功
+	Result   *proto.ModRet   // mysql 等数据库返回的整批插入结果
+}
+
+// BulkReport 批量插入报告
+type BulkReport struct {
+	Total       int             // 总行数
+	Succeeded   int             // 成功行数
+	Failed      int             // 失败行数
+	RowAffected int64           // 影响行数
+	Results     []*proto.ModRet // 每行插入结果，与 rows 一一对应，仅数据库返回每行结果时有值，失败批次对应位置为 nil
+	Chunks      []*BulkChunk    // 每批插入结果
+}
+
+// AllSucceeded 是否全部插入成功
+func (r *BulkReport) AllSucceeded() bool {
+	return r.Failed == 0
+}
+
+// Err 第一个失败批次的错误，全部成功或仅部分行失败（见 Results 的 status）时返回 nil
+func (r *BulkReport) Err() error {
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			return chunk.Err
+		}
+	}
+	return nil
+}
+
+// BulkInsert 分批（批量）插入数据，rows 可以是 []struct / []Map，按行数、编码后的字节数分批，
+// 多批并发执行，可重试失败批次，返回汇总每批结果的插入报告，部分批次失败不影响其他批次。
+// param: table 表名（执行单元名）
+// param: opts 批量插入参数，可以为 nil
+func BulkInsert(ctx context.Context, table string, rows interface{}, opts *BulkOptions) (*BulkReport, error) {
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+
+	coder := opts.Coder
+	if coder == nil {
+		coder = codec.DefaultCodec
+	}
+
+	encodeType := codec.EncodeTypeInsertData
+	if opts.Replace {
+		encodeType = codec.EncodeTypeReplaceData
+	}
+
+	data, err := coder.Encode(encodeType, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := bulkMaps(data)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := splitChunks(maps, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BulkReport{Total: len(maps), Chunks: make([]*BulkChunk, len(chunks))}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	offset := 0
+	for i, chunkRows := range chunks {
+		chunk := &BulkChunk{Offset: offset, Rows: len(chunkRows)}
+		report.Chunks[i] = chunk
+		offset += len(chunkRows)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(chunkRows []map[string]interface{}) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			insertChunk(ctx, table, chunkRows, chunk, opts, coder)
+		}(chunkRows)
+	}
+
+	wg.Wait()
+
+	report.summarize()
+	return report, nil
+}
+
+// insertChunk 插入一批数据，失败时按参数重试
+func insertChunk(ctx context.Context, table string, rows []map[string]interface{},
+	chunk *BulkChunk, opts *BulkOptions, coder codec.Codec) {
+	for {
+		chunk.Attempts++
+
+		q := NewQuery(table).WithCoder(coder)
+		if opts.Replace {
+			q.Op("replace")
+		} else {
+			q.Op("insert")
+		}
+		q.setMap(rows)
+
+		if opts.Client != nil {
+			q.WithClient(opts.Client)
+		}
+
+		var ret interface{}
+		_, chunk.Err = q.Exec(ctx, &ret)
+		if chunk.Err == nil {
+			chunk.Err = decodeChunk(q, ret, chunk)
+			return
+		}
+
+		if chunk.Attempts > opts.Retries || !IsRetryable(chunk.Err) {
+			return
+		}
+
+		if maybeApplied(chunk.Err) && !opts.Replace && !opts.RetryTimeout { // 非幂等的 insert 不重试可能已写入的批次
+			return
+		}
+
+		if opts.RetryBackoff > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.RetryBackoff):
+			}
+		}
+	}
+}
+
+// maybeApplied 请求可能已被服务端执行的错误，例如客户端超时、请求发出后的网络错误，
+// 选址失败、连接失败、服务端排队超时与过载时请求未被执行。
+func maybeApplied(err error) bool {
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return true
+	}
+
+	return e.Code == errs.ErrClientTimeout || e.Code == errs.ErrClientNet || e.Code == errs.ErrClientReadFrame
+}
+
+// decodeChunk 解码插入结果，elastic 返回每行结果数组，mysql 等返回整批结果
+func decodeChunk(q *Query, ret interface{}, chunk *BulkChunk) error {
+	var err error
+	switch ret.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		err = q.GetCoder().Decode(q.ResultType, ret, []interface{}{&chunk.Results})
+	default:
+		chunk.Result = &proto.ModRet{}
+		err = q.GetCoder().Decode(q.ResultType, ret, []interface{}{chunk.Result})
+	}
+
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode, "[request_id=%d] bulk insert result decode error: %v", q.RequestID, err)
+	}
+
+	return nil
+}
+
+// summarize 汇总每批结果
+func (r *BulkReport) summarize() {
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			r.Failed += chunk.Rows
+			continue
+		}
+
+		if chunk.Result != nil {
+			r.RowAffected += chunk.Result.RowAffected
+		}
+
+		if chunk.Results == nil {
+			r.Succeeded += chunk.Rows
+			continue
+		}
+
+		if r.Results == nil {
+			r.Results = make([]*proto.ModRet, r.Total)
+		}
+
+		copy(r.Results[chunk.Offset:chunk.Offset+chunk.Rows], chunk.Results)
+
+		for _, ret := range chunk.Results {
+			if ret.Status == 0 {
+				r.Succeeded++
+				r.RowAffected += ret.RowAffected
+			} else {
+				r.Failed++
+			}
+		}
+
+		r.Failed += chunk.Rows - len(chunk.Results) // 未返回结果的行
+	}
+}
+
+// bulkMaps 将编码后的数据转换为 []map
+func bulkMaps(data interface{}) ([]map[string]interface{}, error) {
+	switch v := data.(type) {
+	case []map[string]interface{}:
+		return v, nil
+	case []Map:
+		maps := make([]map[string]interface{}, len(v))
+		for k, m := range v {
+			maps[k] = m
+		}
+		return maps, nil
+	case []types.Map:
+		maps := make([]map[string]interface{}, len(v))
+		for k, m := range v {
+			maps[k] = m
+		}
+		return maps, nil
+	default:
+		return nil, errs.New(errs.ErrReqParamInvalid, "bulk insert rows must be []struct/[]map")
+	}
+}
+
+// splitChunks 按行数、编码后的字节数分批
+func splitChunks(maps []map[string]interface{}, opts *BulkOptions) ([][]map[string]interface{}, error) {
+	maxRows, maxBytes := opts.ChunkRows, opts.ChunkBytes
+	if maxRows <= 0 {
+		maxRows = defaultBulkChunkRows
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = defaultBulkChunkBytes
+	}
+
+	var chunks [][]map[string]interface{}
+	var chunk []map[string]interface{}
+	var size int
+
+	for _, m := range maps {
+		b, err := json.Api.Marshal(m)
+		if err != nil {
+			return nil, errs.New(errs.ErrClientEncode, "bulk insert row marshal error: "+err.Error())
+		}
+
+		if len(chunk) > 0 && (len(chunk) >= maxRows || size+len(b) > maxBytes) {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+
+		chunk = append(chunk, m)
+		size += len(b)
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
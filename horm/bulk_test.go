@@ -0,0 +1,76 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+func TestBulkInsertRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *errs.Error
+		replace  bool
+		opt      bool
+		attempts int32
+	}{
+		{"insert client timeout", errs.New(errs.ErrClientTimeout, "timeout").(*errs.Error), false, false, 1},
+		{"insert network error", errs.New(errs.ErrClientNet, "reset").(*errs.Error), false, false, 1},
+		{"insert client timeout opt-in", errs.New(errs.ErrClientTimeout, "timeout").(*errs.Error), false, true, 2},
+		{"replace client timeout", errs.New(errs.ErrClientTimeout, "timeout").(*errs.Error), true, false, 2},
+		{"insert connect failed", errs.New(errs.ErrClientConnect, "refused").(*errs.Error), false, false, 2},
+		{"insert server queue timeout", errs.New(errs.ErrServerTimeout, "queue timeout").(*errs.Error), false, false, 2},
+		{"insert server overload", errs.New(errs.ErrServerOverload, "overload").(*errs.Error), false, false, 2},
+		{"insert not retryable", errs.New(errs.ErrReqParamInvalid, "invalid").(*errs.Error), false, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			handler := func(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+				if atomic.LoadInt32(&calls) == 1 {
+					return nil, nil, tt.err
+				}
+				return &proto.ResponseHeader{RequestId: head.RequestId}, []byte(`{"row_affected":1}`), nil
+			}
+
+			opts := &BulkOptions{
+				Retries:      3,
+				RetryTimeout: tt.opt,
+				Replace:      tt.replace,
+				Client:       NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, handler))),
+			}
+
+			rows := []map[string]interface{}{{"id": 1}}
+			report, err := BulkInsert(context.Background(), "student", rows, opts)
+			if err != nil {
+				t.Fatalf("BulkInsert error: %v", err)
+			}
+
+			if calls != tt.attempts || report.Chunks[0].Attempts != int(tt.attempts) {
+				t.Fatalf("attempts %d, want %d", calls, tt.attempts)
+			}
+
+			if succeeded := tt.attempts > 1; report.AllSucceeded() != succeeded {
+				t.Fatalf("all succeeded %v, want %v", report.AllSucceeded(), succeeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/util"
+)
+
+// 默认结果缓存大小
+const (
+	defaultCacheEntries = 10000
+	defaultCacheBytes   = 64 << 20
+)
+
+// ResultCache 本地结果缓存，缓存请求返回的原始结果，命中后仍由执行单元的编解码器解码到接收者。
+// 按 LRU 淘汰，条目数、结果字节数超过上限时淘汰最久未使用的结果，
+// 缓存条目以执行单元的表名和 Query.Cache 指定的标签打标，同一客户端执行新增、修改、删除操作时，自动失效该表名标签的缓存。
+type ResultCache struct {
+	mu          sync.Mutex
+	maxEntries  int
+	maxBytes    int
+	negativeTTL time.Duration
+	bytes       int
+	ll          *list.List
+	entries     map[string]*list.Element
+	tags        map[string]map[string]struct{} // tag -> keys
+}
+
+type cacheEntry struct {
+	key      string
+	header   *proto.ResponseHeader
+	result   []byte
+	expireAt time.Time
+	tags     []string
+}
+
+// NewResultCache 创建本地结果缓存
+// param: maxEntries 最大条目数，小于等于 0 时默认 10000
+// param: maxBytes 缓存结果最大字节数，小于等于 0 时默认 64MB
+// param: negativeTTL 空结果（is_nil）的缓存时间，为 0 时与 Query.Cache 指定的缓存时间相同，小于 0 时不缓存空结果
+func NewResultCache(maxEntries, maxBytes int, negativeTTL time.Duration) *ResultCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = defaultCacheBytes
+	}
+
+	return &ResultCache{
+		maxEntries:  maxEntries,
+		maxBytes:    maxBytes,
+		negativeTTL: negativeTTL,
+		ll:          list.New(),
+		entries:     map[string]*list.Element{},
+		tags:        map[string]map[string]struct{}{},
+	}
+}
+
+// Invalidate 失效指定标签（或表名）的所有缓存
+func (c *ResultCache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if e, ok := c.entries[key]; ok {
+				c.remove(e)
+			}
+		}
+	}
+}
+
+// Purge 清空缓存
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bytes = 0
+	c.ll.Init()
+	c.entries = map[string]*list.Element{}
+	c.tags = map[string]map[string]struct{}{}
+}
+
+// Len 缓存条目数
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *ResultCache) get(key string) (*proto.ResponseHeader, []byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if time.Now().After(entry.expireAt) {
+		c.remove(e)
+		return nil, nil, false
+	}
+
+	c.ll.MoveToFront(e)
+	return entry.header, entry.result, true
+}
+
+func (c *ResultCache) set(key string, header *proto.ResponseHeader,
+	result []byte, ttl time.Duration, tags []string) {
+	if isNilResult(header) && c.negativeTTL != 0 {
+		ttl = c.negativeTTL
+	}
+
+	if ttl <= 0 || len(result) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	entry := &cacheEntry{key: key, header: header, result: result, expireAt: time.Now().Add(ttl), tags: tags}
+	c.entries[key] = c.ll.PushFront(entry)
+	c.bytes += len(result)
+
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *ResultCache) remove(e *list.Element) {
+	entry := e.Value.(*cacheEntry)
+
+	c.ll.Remove(e)
+	delete(c.entries, entry.key)
+	c.bytes -= len(entry.result)
+
+	for _, tag := range entry.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+// cacheable 返回结果是否可以缓存，有错误的结果不缓存
+func cacheable(header *proto.ResponseHeader) bool {
+	if header == nil || (header.Err != nil && header.Err.Code != 0) {
+		return false
+	}
+
+	for _, e := range header.RspErrs {
+		if e != nil && e.Code != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNilResult 是否为空结果
+func isNilResult(header *proto.ResponseHeader) bool {
+	if header.IsNil {
+		return true
+	}
+
+	if len(header.RspNils) == 0 {
+		return false
+	}
+
+	for _, isNil := range header.RspNils {
+		if !isNil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheTags 缓存标签，包括所有执行单元的表名与指定标签
+func cacheTags(q *Query) []string {
+	tags := append([]string{}, q.CacheTags...)
+	walkQuery(q, func(s *Query) {
+		name, _ := util.Alias(s.Unit.Name)
+		tags = append(tags, name)
+	})
+	return tags
+}
+
+// writeTables 新增、修改、删除操作的表名，直接输入的查询语句、字节码无法判断是否只读，同样视为写操作
+func writeTables(q *Query) []string {
+	var tables []string
+	walkQuery(q, func(s *Query) {
+		switch consts.OpType(s.Unit.Op) {
+		case consts.OpTypeAdd, consts.OpTypeMod, consts.OpTypeDel, consts.OpTypeDrop:
+		default:
+			if s.Unit.Query == "" && len(s.Unit.Bytes) == 0 {
+				return
+			}
+		}
+
+		name, _ := util.Alias(s.Unit.Name)
+		tables = append(tables, name)
+	})
+	return tables
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+// mockInterceptor 不发送网络请求，直接返回 handler 的结果，并统计请求次数
+func mockInterceptor(calls *int32, handler func(head *proto.RequestHeader,
+	body []byte) (*proto.ResponseHeader, []byte, error)) Interceptor {
+	return func(ctx context.Context, head *proto.RequestHeader,
+		reqBody []byte, next Invoker) (*proto.ResponseHeader, []byte, error) {
+		atomic.AddInt32(calls, 1)
+		return handler(head, reqBody)
+	}
+}
+
+func okHandler(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+	return &proto.ResponseHeader{RequestId: head.RequestId, QueryMode: head.QueryMode}, []byte(`{"id":1}`), nil
+}
+
+func TestResultCacheCredentialIsolation(t *testing.T) {
+	cache := NewResultCache(0, 0, 0)
+
+	var calls int32
+	mock := WithInterceptor(mockInterceptor(&calls, okHandler))
+
+	cli1 := NewClient("ws_test.app1.server1.service1", WithResultCache(cache), mock).(*cli)
+	cli2 := NewClient("ws_test.app1.server1.service1", WithResultCache(cache), mock, WithAppID(10003)).(*cli)
+	cli3 := NewClient("ws_test.app1.server1.service1", WithResultCache(cache), mock, WithSecret("other")).(*cli)
+
+	find := func(c *cli) {
+		q := NewQuery("student").Find(Where{"id": 1}).Cache(time.Minute)
+		if _, _, err := c.exec(context.Background(), 1, q); err != nil {
+			t.Fatalf("exec error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		c     *cli
+		calls int32
+	}{
+		{"first request", cli1, 1},
+		{"cache hit", cli1, 1},
+		{"other appid", cli2, 2},
+		{"other appid cache hit", cli2, 2},
+		{"other secret", cli3, 3},
+	}
+
+	for _, tt := range tests {
+		find(tt.c)
+		if got := atomic.LoadInt32(&calls); got != tt.calls {
+			t.Fatalf("%s: requests %d, want %d", tt.name, got, tt.calls)
+		}
+	}
+}
+
+func TestResultCacheInvalidateOnWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func() *Query
+		fail  bool
+	}{
+		{"update", func() *Query { return NewQuery("student").Update(Map{"age": 10}, Where{"id": 1}) }, false},
+		{"update timeout", func() *Query { return NewQuery("student").Update(Map{"age": 10}, Where{"id": 1}) }, true},
+		{"raw statement", func() *Query { return NewQuery("student").Source("UPDATE student SET age=10") }, false},
+		{"raw statement failed", func() *Query { return NewQuery("student").Source("DELETE FROM student") }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewResultCache(0, 0, 0)
+
+			var calls int32
+			fail := false
+			handler := func(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+				if fail {
+					return nil, nil, errs.New(errs.ErrClientTimeout, "timeout")
+				}
+				return okHandler(head, body)
+			}
+
+			c := NewClient("ws_test.app1.server1.service1",
+				WithResultCache(cache), WithInterceptor(mockInterceptor(&calls, handler))).(*cli)
+
+			q := NewQuery("student").Find(Where{"id": 1}).Cache(time.Minute)
+			if _, _, err := c.exec(context.Background(), 1, q); err != nil {
+				t.Fatalf("exec error: %v", err)
+			}
+
+			if cache.Len() != 1 {
+				t.Fatalf("cache len %d, want 1", cache.Len())
+			}
+
+			fail = tt.fail
+			_, _, _ = c.exec(context.Background(), 1, tt.write())
+
+			if cache.Len() != 0 {
+				t.Fatalf("cache is not invalidated after write")
+			}
+		})
+	}
+}
+
+func TestResultCacheOptIn(t *testing.T) {
+	var calls int32
+	c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler))).(*cli)
+
+	for i := 0; i < 2; i++ {
+		q := NewQuery("student").Find(Where{"id": 1}).Cache(time.Minute)
+		if _, _, err := c.exec(context.Background(), 1, q); err != nil {
+			t.Fatalf("exec error: %v", err)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("requests %d, want 2 when client has no result cache", calls)
+	}
+}
+
+func TestResultCacheLRU(t *testing.T) {
+	header := &proto.ResponseHeader{}
+	nilHeader := &proto.ResponseHeader{IsNil: true}
+
+	c := NewResultCache(2, 0, -1)
+	c.set("a", header, []byte("1"), time.Minute, []string{"student"})
+	c.set("b", header, []byte("2"), time.Minute, []string{"course"})
+	c.get("a")
+	c.set("c", header, []byte("3"), time.Minute, nil)
+
+	if _, _, ok := c.get("b"); ok {
+		t.Fatalf("least recently used entry is not evicted")
+	}
+
+	if _, _, ok := c.get("a"); !ok {
+		t.Fatalf("recently used entry is evicted")
+	}
+
+	c.set("d", nilHeader, []byte("null"), time.Minute, nil)
+	if _, _, ok := c.get("d"); ok {
+		t.Fatalf("nil result is cached when negative ttl < 0")
+	}
+
+	c.Invalidate("student")
+	if _, _, ok := c.get("a"); ok {
+		t.Fatalf("entry is not invalidated by tag")
+	}
+
+	c.set("e", header, []byte("5"), time.Nanosecond, nil)
+	time.Sleep(time.Millisecond)
+	if _, _, ok := c.get("e"); ok {
+		t.Fatalf("expired entry is returned")
+	}
+}
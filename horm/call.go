@@ -0,0 +1,114 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/go-horm/horm/stats"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// call 一次 Exec/PExec/CompExec 调用，结束时结束 span 并上报监控指标
+type call struct {
+	ctx      context.Context
+	begin    time.Time
+	caller   string
+	span     trace.Span
+	unitErrs map[*Query]error // 并行查询、复合查询各执行单元的错误
+
+	compNotNil map[*Query]bool // 复合查询子查询存在非空结果
+	result     []byte          // 返回结果，用于 debug 日志
+}
+
+// startCall 开始一次调用
+func (o *cli) startCall(ctx context.Context, name string, q *Query) (context.Context, *call) {
+	opts := o.getOptions(q)
+
+	c := &call{begin: time.Now(), caller: opts.Caller}
+	if c.caller == "" {
+		c.caller = opts.Name
+	}
+
+	ctx, c.span = startSpan(ctx, opts, name, q)
+	c.ctx = ctx
+	return ctx, c
+}
+
+// unitError 记录并行查询执行单元的错误
+func (c *call) unitError(q *Query, err error) {
+	if c.unitErrs == nil {
+		c.unitErrs = map[*Query]error{}
+	}
+	c.unitErrs[q] = err
+}
+
+// end 结束调用，按执行单元上报请求数、耗时与解码失败数，并打印慢查询、错误、debug 日志，
+// 返回包装后的结构化错误
+func (c *call) end(q *Query, err error) error {
+	endSpan(c.span, err)
+
+	if err == ErrDryRun { // 未发送请求，不上报
+		return err
+	}
+
+	unit := ""
+	if q.GetHead().next == nil {
+		unit = q.GetHead().Unit.Name
+	}
+	err = wrapError(err, unit, q.GetHead())
+
+	cost := time.Since(c.begin)
+
+	walkQuery(q.GetHead(), func(s *Query) {
+		unitErr := err
+		if _, ok := err.(*MultiError); ok || unitErr == nil { // 并行查询按执行单元上报
+			unitErr = c.unitErrs[s]
+		}
+
+		code := errCode(unitErr)
+		labels := stats.Labels{"caller": c.caller, "name": s.Unit.Name, "op": s.Unit.Op}
+
+		stats.ObserveHistogram(stats.RequestDuration, labels, cost.Seconds())
+		if code == errs.ErrClientDecode {
+			stats.AddCounter(stats.DecodeFailTotal, labels, 1)
+		}
+
+		labels["code"] = strconv.Itoa(code)
+		stats.AddCounter(stats.RequestTotal, labels, 1)
+
+		logUnit(c.ctx, s, cost, unitErr, c.result)
+	})
+
+	return err
+}
+
+// errCode 错误码，成功返回 0，非 errs.Error 错误返回 -1
+func errCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return -1
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/horm-database/common/types"
 	"github.com/horm-database/common/util"
 	"github.com/horm-database/go-horm/horm/client"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var GlobalClient Client // 全局查询语句执行客户端
@@ -46,17 +47,19 @@ type Client interface {
 // param: opts 参数配置
 func NewClient(name string, opts ...Option) Client {
 	return &cli{
-		name: name,
-		opts: opts,
-		c:    client.DefaultClient,
+		name:    name,
+		opts:    opts,
+		c:       client.DefaultClient,
+		flights: &flightGroup{},
 	}
 }
 
 // cli 查询语句执行客户端 Client 实现
 type cli struct {
-	name string
-	opts []Option
-	c    *client.Client
+	name    string
+	opts    []Option
+	c       *client.Client
+	flights *flightGroup // 只读请求合并
 }
 
 // SetGlobalClient 设置全局查询语句执行客户端。
@@ -68,7 +71,11 @@ func SetGlobalClient(name string, opts ...Option) {
 
 // Exec 单执行单元 result 接收结果的指针，可以不传，最多一个
 func (o *cli) Exec(ctx context.Context, q *Query, retReceiver ...interface{}) (isNil bool, err error) {
+	ctx, c := o.startCall(ctx, "Exec", q)
+	defer func() { err = c.end(q, err) }()
+
 	header, result, err := o.exec(ctx, consts.QueryModeSingle, q)
+	c.result = result
 	if err != nil {
 		return false, err
 	}
@@ -105,8 +112,12 @@ func (o *cli) Exec(ctx context.Context, q *Query, retReceiver ...interface{}) (i
 }
 
 // PExec 执行并行查询（多个执行单元并发，没有嵌套子查询）
-func (o *cli) PExec(ctx context.Context, q *Query) error {
+func (o *cli) PExec(ctx context.Context, q *Query) (err error) {
+	ctx, c := o.startCall(ctx, "PExec", q)
+	defer func() { err = c.end(q, err) }()
+
 	header, result, err := o.exec(ctx, consts.QueryModeParallel, q)
+	c.result = result
 	if err != nil {
 		return err
 	}
@@ -118,50 +129,78 @@ func (o *cli) PExec(ctx context.Context, q *Query) error {
 			"result decode to ret receiver error: %v, resp=[%s]", q.RequestID, err, string(result))
 	}
 
+	pResult := &PExecResult{}
+
 	query := q.GetHead()
-	for {
-		if query == nil {
-			return nil
-		}
+	for ; query != nil; query = query.next {
+		unit := &UnitResult{Key: query.Key, Name: query.Unit.Name}
+		pResult.Units = append(pResult.Units, unit)
 
 		if header.RspErrs != nil {
 			rspErr, ok := header.RspErrs[query.Key]
 			if ok && rspErr != nil && rspErr.Code != 0 {
+				e := &errs.Error{
+					Type: errs.EType(rspErr.Type),
+					Code: int(rspErr.Code),
+					Msg:  rspErr.Msg,
+				}
+
+				unit.Err = wrapError(e, query.Unit.Name, q.GetHead())
+				c.unitError(query, unit.Err)
 				if query.RespError != nil {
-					*query.RespError = &errs.Error{
-						Type: errs.EType(rspErr.Type),
-						Code: int(rspErr.Code),
-						Msg:  rspErr.Msg,
-					}
+					*query.RespError = unit.Err
 				}
 
-				query = query.next
 				continue
 			}
 		}
 
 		if header.RspNils != nil {
 			isNil, ok := header.RspNils[query.Key]
-			if ok && isNil && query.IsNil != nil {
-				*query.IsNil = true
+			if ok && isNil {
+				unit.IsNil = true
+				if query.IsNil != nil {
+					*query.IsNil = true
+				}
 			}
 		}
 
 		if ret, ok := rspData[query.Key]; ok {
-			err = query.GetCoder().Decode(query.ResultType, ret, query.Receiver)
-			if err != nil && query.RespError != nil {
-				*query.RespError = errs.Newf(errs.ErrClientDecode,
-					"[request_id=%d] %v, result=[%s]", query.RequestID, err, types.ToString(result))
+			decodeErr := query.GetCoder().Decode(query.ResultType, ret, query.Receiver)
+			if decodeErr != nil {
+				e := errs.Newf(errs.ErrClientDecode,
+					"[request_id=%d] %v, result=[%s]", query.RequestID, decodeErr, types.ToString(result))
+
+				unit.Err = wrapError(e, query.Unit.Name, q.GetHead())
+				unit.DecodeFailed = true
+				c.unitError(query, unit.Err)
+				if query.RespError != nil {
+					*query.RespError = unit.Err
+				}
 			}
 		}
+	}
 
-		query = query.next
+	head := q.GetHead()
+	if head.PResult != nil {
+		*head.PResult = *pResult
 	}
+
+	if o.getOptions(head).Strict {
+		return pResult.Err()
+	}
+
+	return nil
 }
 
-// CompExec 执行复合查询（包含嵌套子查询）
-func (o *cli) CompExec(ctx context.Context, q *Query, retReceiver interface{}) error {
+// CompExec 执行复合查询（包含嵌套子查询），retReceiver 接收整个返回，可以为 nil，
+// 各执行单元也可以通过 WithReceiver 接收本单元的结果、是否为空与错误。
+func (o *cli) CompExec(ctx context.Context, q *Query, retReceiver interface{}) (err error) {
+	ctx, c := o.startCall(ctx, "CompExec", q)
+	defer func() { err = c.end(q, err) }()
+
 	header, result, err := o.exec(ctx, consts.QueryModeCompound, q)
+	c.result = result
 	if err != nil {
 		return err
 	}
@@ -174,10 +213,16 @@ func (o *cli) CompExec(ctx context.Context, q *Query, retReceiver interface{}) e
 		}
 	}
 
-	err = json.Api.Unmarshal(result, retReceiver)
-	if err != nil {
-		return errs.Newf(errs.ErrClientDecode, "[request_id=%d] "+
-			"result decode to ret receiver error: %v, resp=[%s]", q.RequestID, err, string(result))
+	if retReceiver != nil {
+		err = json.Api.Unmarshal(result, retReceiver)
+		if err != nil {
+			return errs.Newf(errs.ErrClientDecode, "[request_id=%d] "+
+				"result decode to ret receiver error: %v, resp=[%s]", q.RequestID, err, string(result))
+		}
+	}
+
+	if head := q.GetHead(); hasUnitReceiver(head) { // 按执行单元解码到 WithReceiver 设置的接收者
+		return c.decodeComp(head, result)
 	}
 
 	return nil
@@ -196,27 +241,17 @@ func (o *cli) exec(ctx context.Context, mode uint32, q *Query) (*proto.ResponseH
 		return nil, nil, errs.New(errs.ErrClientEncode, "client unit marshal error: "+err.Error())
 	}
 
-	opts := getOptions(o.name)
+	opts := o.getOptions(q)
 
-	if len(o.opts) > 0 {
-		for _, opt := range o.opts {
-			opt(opts)
-		}
-	}
-
-	timeout := opts.Timeout
-	if deadline, ok := ctx.Deadline(); ok { // 如果 context 超时时间比数据库设置的超时时间要短，则取 context 超时时间。
-		leftMillisecond := uint32(deadline.Sub(time.Now()) / time.Millisecond)
-		if leftMillisecond < opts.Timeout {
-			timeout = leftMillisecond
+	if mode == consts.QueryModeSingle && q.Timeout > 0 { // 单执行单元查询，执行单元超时即请求超时
+		if timeout := uint32(q.Timeout.Milliseconds()); opts.Timeout == 0 || timeout < opts.Timeout {
+			opts.Timeout = timeout
 		}
 	}
 
 	head := proto.RequestHeader{}
 	head.Version = Version
 	head.QueryMode = mode
-	head.Timestamp = uint64(time.Now().UnixMilli())
-	head.Timeout = timeout
 
 	if opts.Caller != "" {
 		head.Caller = opts.Caller
@@ -227,13 +262,21 @@ func (o *cli) exec(ctx context.Context, mode uint32, q *Query) (*proto.ResponseH
 	head.Callee = "server.access.api/Query"
 	head.Appid = opts.Appid
 	head.Ip = opts.LocalIP
-	head.AuthRand = uint32(rand.Intn(99999999))
 
 	if head.Ip == "" {
 		head.Ip = util.GetLocalIP()
 	}
 
-	if q.Compress { // 压缩
+	var compressType uint32 // 客户端压缩请求体的算法
+	if q.CompressType != 0 {
+		compressType = q.CompressType
+	} else if opts.Compress != 0 && len(q.RequestBody) >= opts.CompressThreshold { // 超过阈值自动压缩
+		compressType = opts.Compress
+	}
+
+	if compressType != 0 {
+		head.Compress = compressType
+	} else if q.Compress { // 压缩
 		head.Compress = consts.Compression
 	}
 
@@ -246,18 +289,28 @@ func (o *cli) exec(ctx context.Context, mode uint32, q *Query) (*proto.ResponseH
 
 	if q.TraceID != "" {
 		head.TraceId = q.TraceID
+	} else if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		head.TraceId = sc.TraceID().String()
 	}
 
-	// 签名
-	md5Str := fmt.Sprintf("%d%s%d%d%d%s%d%d%s%d%d%d", head.Appid, opts.Secret,
-		head.RequestType, head.QueryMode, head.RequestId, head.TraceId, head.Timestamp,
-		head.Timeout, head.Caller, head.Compress, head.AuthRand, head.Version)
+	// metadata 写入请求头保留字段 bak 透传到服务端，需服务端支持
+	if opts.MetadataHeader {
+		injectTraceContext(ctx, opts)
+	}
 
-	head.Sign = crypto.MD5Str(md5Str)
+	if opts.MetadataHeader && len(opts.Metadata) > 0 {
+		metadata, err := json.Api.Marshal(opts.Metadata)
+		if err != nil {
+			return nil, nil, errs.New(errs.ErrClientEncode, "client metadata marshal error: "+err.Error())
+		}
+		head.Bak = string(metadata)
+	}
 
 	reqParam := client.ReqParam{
 		WorkspaceID: opts.WorkspaceID,
 		Encryption:  opts.Encryption,
+		Crypter:     opts.Crypter,
+		Compress:    compressType,
 		Token:       opts.Token,
 		Target:      opts.Target,
 	}
@@ -266,5 +319,134 @@ func (o *cli) exec(ctx context.Context, mode uint32, q *Query) (*proto.ResponseH
 	reqParam.Location.Zone = opts.Location.Zone
 	reqParam.Location.Compus = opts.Location.Compus
 
-	return o.c.Invoke(ctx, &head, q.RequestBody, &reqParam)
+	if opts.DryRun { // 仅构造请求头与请求体，不发送请求
+		signHead(ctx, opts, &head)
+		q.RequestHeader = &head
+		return nil, nil, ErrDryRun
+	}
+
+	cache := opts.Cache
+
+	if !readOnly(q) { // 新增、修改、删除操作，失效该表的本地缓存
+		respHeader, respBody, err := o.send(ctx, opts, q, &head, &reqParam)
+		if cache != nil { // 请求失败（例如超时）时写操作也可能已经生效，同样需要失效
+			cache.Invalidate(writeTables(q)...)
+		}
+		return respHeader, respBody, err
+	}
+
+	key := requestKey(o.name, opts, mode, q.RequestBody)
+
+	if cache != nil && q.CacheTTL > 0 {
+		if respHeader, respBody, ok := cache.get(key); ok {
+			return respHeader, respBody, nil
+		}
+	}
+
+	var respHeader *proto.ResponseHeader
+	var respBody []byte
+	if opts.Coalesce { // 合并并发的相同只读请求
+		// 共享请求写入私有的查询副本，发起方提前返回后不会再写入调用方的查询
+		shared := &Query{RequestBody: q.RequestBody, RespInfo: &ResponseInfo{}}
+		respHeader, respBody, err = o.flights.do(ctx, o.name, key,
+			func(ctx context.Context) (*proto.ResponseHeader, []byte, error) {
+				return o.send(ctx, opts, shared, &head, &reqParam)
+			})
+
+		if ctx.Err() == nil && shared.RespInfo.Attempts > 0 { // 发起方等到了结果，回填节点地址与返回信息
+			q.addr = shared.addr
+			if q.RespInfo != nil {
+				*q.RespInfo = *shared.RespInfo
+			}
+		}
+	} else {
+		respHeader, respBody, err = o.send(ctx, opts, q, &head, &reqParam)
+	}
+
+	if err == nil && cache != nil && q.CacheTTL > 0 && cacheable(respHeader) {
+		cache.set(key, respHeader, respBody, q.CacheTTL, cacheTags(q))
+	}
+
+	return respHeader, respBody, err
+}
+
+// send 发送请求，失败时按重试策略重试
+func (o *cli) send(ctx context.Context, opts *Options, q *Query,
+	head *proto.RequestHeader, reqParam *client.ReqParam) (*proto.ResponseHeader, []byte, error) {
+	if q.RespInfo != nil {
+		reqParam.Timing = &client.Timing{}
+	}
+
+	read := readOnly(q)
+	for attempt := 1; ; attempt++ {
+		respHeader, respBody, err := o.invoke(ctx, opts, head, q.RequestBody, reqParam)
+		q.RespInfo.set(attempt, head, reqParam)
+		q.addr = reqParam.Address
+
+		if err == nil || !opts.Retry.retryable(attempt, read, err) {
+			return respHeader, respBody, err
+		}
+
+		if opts.Retry.Backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return respHeader, respBody, err
+			case <-time.After(opts.Retry.Backoff):
+			}
+		}
+	}
+}
+
+// getOptions 获取调用参数，优先级：查询参数 > 客户端参数 > 配置文件
+func (o *cli) getOptions(q *Query) *Options {
+	opts := getOptions(o.name).clone()
+
+	for _, opt := range o.opts {
+		opt(opts)
+	}
+
+	for _, opt := range q.CallOptions {
+		opt(opts)
+	}
+
+	return opts
+}
+
+// invoke 发起一次请求，每次请求（包括重试）都需要重新计算超时时间、随机数与签名
+func (o *cli) invoke(ctx context.Context, opts *Options, head *proto.RequestHeader,
+	reqBody []byte, reqParam *client.ReqParam) (*proto.ResponseHeader, []byte, error) {
+	signHead(ctx, opts, head)
+
+	if reqParam.Timing != nil { // 每次请求重新统计耗时
+		*reqParam.Timing = client.Timing{}
+	}
+
+	invoker := func(ctx context.Context,
+		head *proto.RequestHeader, reqBody []byte) (*proto.ResponseHeader, []byte, error) {
+		return o.c.Invoke(ctx, head, reqBody, reqParam)
+	}
+
+	return chainInterceptors(opts.Interceptors, invoker)(ctx, head, reqBody)
+}
+
+// signHead 设置请求头的时间戳、超时时间、随机数并签名
+func signHead(ctx context.Context, opts *Options, head *proto.RequestHeader) {
+	timeout := opts.Timeout
+	if deadline, ok := ctx.Deadline(); ok { // 如果 context 超时时间比数据库设置的超时时间要短，则取 context 超时时间。
+		leftMillisecond := uint32(deadline.Sub(time.Now()) / time.Millisecond)
+		if leftMillisecond < opts.Timeout {
+			timeout = leftMillisecond
+		}
+	}
+
+	head.Timestamp = uint64(time.Now().UnixMilli())
+	head.Timeout = timeout
+	head.AuthRand = uint32(rand.Intn(99999999))
+
+	// 签名
+	md5Str := fmt.Sprintf("%d%s%d%d%d%s%d%d%s%d%d%d", head.Appid, opts.Secret,
+		head.RequestType, head.QueryMode, head.RequestId, head.TraceId, head.Timestamp,
+		head.Timeout, head.Caller, head.Compress, head.AuthRand, head.Version)
+
+	head.Sign = crypto.MD5Str(md5Str)
 }
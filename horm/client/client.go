@@ -28,6 +28,9 @@ import (
 	"github.com/horm-database/common/naming"
 	"github.com/horm-database/common/proto"
 	"github.com/horm-database/common/types"
+	"github.com/horm-database/go-horm/horm/stats"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DefaultClient 默认通用客户端（thread-safe）
@@ -38,8 +41,12 @@ type Client struct{}
 type ReqParam struct {
 	WorkspaceID int
 	Encryption  int8
+	Crypter     int8
+	Compress    uint32 // 客户端压缩请求体的算法，0 表示不压缩请求体
 	Token       string
 	Target      string
+	Timing      *Timing // 耗时明细接收，为 nil 时不统计
+	Address     string  // 返回本次请求的节点地址
 	Location    struct {
 		Region string
 		Zone   string
@@ -50,6 +57,8 @@ type ReqParam struct {
 // Invoke 调用服务端接口
 func (c *Client) Invoke(ctx context.Context, head *proto.RequestHeader,
 	reqBody []byte, reqParam *ReqParam) (*proto.ResponseHeader, []byte, error) {
+	begin := time.Now()
+
 	ctx, msg := codec.NewMessage(ctx)
 	defer codec.RecycleMessage(msg)
 
@@ -68,13 +77,24 @@ func (c *Client) Invoke(ctx context.Context, head *proto.RequestHeader,
 		return nil, nil, err
 	}
 
+	opts.Timing = reqParam.Timing
+
 	if opts.Timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
 		defer cancel()
 	}
 
-	return invoke(ctx, reqBody, opts)
+	respHeader, respBody, err := invoke(ctx, reqBody, opts)
+	if addr := msg.RemoteAddr(); addr != nil {
+		reqParam.Address = addr.String()
+	}
+
+	if opts.Timing != nil {
+		opts.Timing.Total = time.Since(begin)
+	}
+
+	return respHeader, respBody, err
 }
 
 func (c *Client) getOptions(msg *codec.Msg, target string, timeout time.Duration) (*Options, error) {
@@ -111,12 +131,19 @@ func roundTrip(ctx context.Context, reqBody []byte, opts *Options) (*proto.Respo
 	}
 
 	// call backend service
+	stats.AddCounter(stats.SentBytesTotal, stats.Labels{"node": opts.Address}, float64(len(reqBuf)))
 	respBuf, err := opts.Transport.RoundTrip(ctx, reqBuf, opts)
 	if err != nil {
 		return nil, nil, err
 	}
+	stats.AddCounter(stats.ReceivedBytesTotal, stats.Labels{"node": opts.Address}, float64(len(respBuf)))
 
+	decodeBegin := time.Now()
 	respHeader, respBodyBuf, err := opts.Codec.Decode(msg, respBuf)
+	if opts.Timing != nil {
+		opts.Timing.Decode = time.Since(decodeBegin)
+	}
+
 	if err != nil {
 		return nil, nil, errs.New(errs.ErrClientDecode, "client codec Decode: "+err.Error())
 	}
@@ -162,16 +189,28 @@ func invoke(ctx context.Context, reqBody []byte, opts *Options) (*proto.Response
 	msg := codec.Message(ctx)
 
 	// select a node of the backend service
-	node, err := selectNode(ctx, opts)
+	selectBegin := time.Now()
+	selectCtx, span := startSpan(ctx, "select")
+	node, err := selectNode(selectCtx, opts)
+	endSpan(span, err)
+	if opts.Timing != nil {
+		opts.Timing.Select = time.Since(selectBegin)
+	}
+
 	if err != nil {
 		return nil, nil, err
 	}
 
 	resolveRemoteAddr(msg, node.Network, node.Address)
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("horm.node", node.Address))
+
 	// start to process the next filter and report
 	begin := time.Now()
-	respHeader, result, err := roundTrip(ctx, reqBody, opts)
+	rtCtx, span := startSpan(ctx, "round_trip", attribute.String("net.peer.name", node.Address),
+		attribute.String("net.transport", node.Network))
+	respHeader, result, err := roundTrip(rtCtx, reqBody, opts)
+	endSpan(span, err)
 	cost := time.Since(begin)
 
 	if e, ok := err.(*errs.Error); ok &&
@@ -183,6 +222,8 @@ func invoke(ctx context.Context, reqBody []byte, opts *Options) (*proto.Response
 		opts.Selector.Report(node, cost, err)
 	}
 
+	reportNode(node, err)
+
 	// back pass the node info
 	if addr := msg.RemoteAddr(); addr != nil {
 		opts.Node.set(node, addr.String(), cost)
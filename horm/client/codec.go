@@ -50,6 +50,11 @@ func (c *clientCodec) Encode(msg *codec.Msg, reqBody []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	reqBody, err = compress(reqParam.Compress, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("client compress request body error: %v", err)
+	}
+
 	reqHeaderBuf, err := proto.Marshal(reqHeader)
 	if err != nil {
 		return nil, err
@@ -67,6 +72,11 @@ func (c *clientCodec) Encode(msg *codec.Msg, reqBody []byte) ([]byte, error) {
 			reqParam.WorkspaceID,
 			types.StringToBytes(reqParam.Token), reqBuf)
 	} else if reqParam.Encryption == codec.FrameTypeEncrypt {
+		if reqParam.Crypter != CrypterDefault {
+			// 指定算法加密包
+			return encryptFrame(reqParam, reqBuf)
+		}
+
 		// 加密包
 		encryptFrameHead := codec.NewEncryptFrameHead()
 		return encryptFrameHead.Construct(
@@ -79,6 +89,19 @@ func (c *clientCodec) Encode(msg *codec.Msg, reqBody []byte) ([]byte, error) {
 
 // Decode It decodes respBuf into respBody.
 func (c *clientCodec) Decode(msg *codec.Msg, respBuf []byte) (*cp.ResponseHeader, []byte, error) {
+	if isCryptFrame(respBuf) {
+		reqParam, ok := msg.FrameCodec().(*ReqParam)
+		if !ok {
+			return nil, nil, errors.New("client decode crypt frame without request param")
+		}
+
+		var err error
+		respBuf, err = decryptFrame(reqParam, respBuf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("client decrypt frame error: %v", err)
+		}
+	}
+
 	if len(respBuf) < int(frameHeadLen) {
 		return nil, nil, errors.New("client decode rsp buf len invalid")
 	}
@@ -117,7 +140,12 @@ func (c *clientCodec) Decode(msg *codec.Msg, respBuf []byte) (*cp.ResponseHeader
 		return nil, nil, err
 	}
 
-	return respHeader, respBuf[end:], nil
+	respBody, err := decompress(respHeader.Compress, respBuf[end:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("client decompress response body error: %v", err)
+	}
+
+	return respHeader, respBody, nil
 }
 
 func getRequestHead(msg *codec.Msg) (*cp.RequestHeader, error) {
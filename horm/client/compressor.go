@@ -0,0 +1,167 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	cc "github.com/horm-database/common/compress"
+	"github.com/horm-database/common/consts"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+func init() {
+	RegisterCompressor(CompressTypeGzip, &gzipCompressor{})
+	RegisterCompressor(CompressTypeZstd, newZstdCompressor())
+	RegisterCompressor(CompressTypeSnappy, &snappyCompressor{})
+	RegisterCompressor(CompressTypeLZ4, &lz4Compressor{})
+}
+
+// 压缩算法，即请求头/返回头的 compress 字段。协议只定义了 0-不压缩、1-压缩（gzip），
+// zstd、snappy、lz4 为扩展算法 id，需要服务端支持后才能使用，客户端不会默认启用。
+const (
+	CompressTypeNone   uint32 = 0                  // 不压缩
+	CompressTypeGzip   uint32 = consts.Compression // gzip
+	CompressTypeZstd   uint32 = 2                  // zstd，需服务端支持
+	CompressTypeSnappy uint32 = 3                  // snappy，需服务端支持
+	CompressTypeLZ4    uint32 = 4                  // lz4，需服务端支持
+)
+
+// Compressor 帧体压缩算法
+type Compressor interface {
+	Compress(in []byte) ([]byte, error)   // 压缩
+	Decompress(in []byte) ([]byte, error) // 解压
+}
+
+var (
+	compressors = make(map[uint32]Compressor)
+)
+
+// RegisterCompressor registers a Compressor by compress type.
+func RegisterCompressor(typ uint32, c Compressor) {
+	compressors[typ] = c
+}
+
+// GetCompressor gets a Compressor by compress type.
+func GetCompressor(typ uint32) Compressor {
+	c := compressors[typ]
+	return c
+}
+
+// gzipCompressor 请求体为 json，与服务端一致使用 common/compress 的 gzip 压缩与解压
+type gzipCompressor struct{}
+
+// Compress implements Compressor.Compress.
+func (c *gzipCompressor) Compress(in []byte) ([]byte, error) {
+	return cc.JsonMarshalAndCompress(json.RawMessage(in))
+}
+
+// Decompress implements Compressor.Decompress.
+func (c *gzipCompressor) Decompress(in []byte) ([]byte, error) {
+	return cc.Decompress(in)
+}
+
+// zstdCompressor encoder 与 decoder 的 EncodeAll/DecodeAll 是并发安全的，全局共用一个即可。
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCompressor() *zstdCompressor {
+	encoder, _ := zstd.NewWriter(nil)
+	decoder, _ := zstd.NewReader(nil)
+	return &zstdCompressor{
+		encoder: encoder,
+		decoder: decoder,
+	}
+}
+
+// Compress implements Compressor.Compress.
+func (c *zstdCompressor) Compress(in []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(in, nil), nil
+}
+
+// Decompress implements Compressor.Decompress.
+func (c *zstdCompressor) Decompress(in []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(in, nil)
+}
+
+type snappyCompressor struct{}
+
+// Compress implements Compressor.Compress.
+func (c *snappyCompressor) Compress(in []byte) ([]byte, error) {
+	return snappy.Encode(nil, in), nil
+}
+
+// Decompress implements Compressor.Decompress.
+func (c *snappyCompressor) Decompress(in []byte) ([]byte, error) {
+	return snappy.Decode(nil, in)
+}
+
+type lz4Compressor struct{}
+
+// Compress implements Compressor.Compress.
+func (c *lz4Compressor) Compress(in []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := lz4.NewWriter(buf)
+	if _, err := w.Write(in); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Compressor.Decompress.
+func (c *lz4Compressor) Decompress(in []byte) ([]byte, error) {
+	return ioutil.ReadAll(lz4.NewReader(bytes.NewReader(in)))
+}
+
+// compress 按请求头指定的算法压缩请求体
+func compress(typ uint32, body []byte) ([]byte, error) {
+	if typ == CompressTypeNone || len(body) == 0 {
+		return body, nil
+	}
+
+	c := GetCompressor(typ)
+	if c == nil {
+		return nil, fmt.Errorf("compressor %d not exist", typ)
+	}
+
+	return c.Compress(body)
+}
+
+// decompress 按返回头指定的算法解压返回体
+func decompress(typ uint32, body []byte) ([]byte, error) {
+	if typ == CompressTypeNone || len(body) == 0 {
+		return body, nil
+	}
+
+	c := GetCompressor(typ)
+	if c == nil {
+		return nil, fmt.Errorf("compressor %d not exist", typ)
+	}
+
+	return c.Decompress(body)
+}
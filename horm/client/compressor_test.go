@@ -0,0 +1,81 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	body := []byte(`[{"name":"student","op":"insert","data":{"name":"` +
+		string(bytes.Repeat([]byte("horm"), 256)) + `"}}]`)
+
+	tests := []struct {
+		name string
+		typ  uint32
+	}{
+		{"none", CompressTypeNone},
+		{"gzip", CompressTypeGzip},
+		{"zstd", CompressTypeZstd},
+		{"snappy", CompressTypeSnappy},
+		{"lz4", CompressTypeLZ4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := compress(tt.typ, body)
+			if err != nil {
+				t.Fatalf("compress error: %v", err)
+			}
+
+			if tt.typ != CompressTypeNone && len(compressed) >= len(body) {
+				t.Fatalf("compressed len %d, not less than body len %d", len(compressed), len(body))
+			}
+
+			got, err := decompress(tt.typ, compressed)
+			if err != nil {
+				t.Fatalf("decompress error: %v", err)
+			}
+
+			// gzip 与服务端一致按 json 编码压缩，结尾会多一个换行
+			if !bytes.Equal(bytes.TrimRight(got, "\n"), body) {
+				t.Fatalf("decompressed body not equal to origin body")
+			}
+		})
+	}
+}
+
+func TestCompressUnknownType(t *testing.T) {
+	if _, err := compress(99, []byte("{}")); err == nil {
+		t.Fatalf("compress with unknown type succeeded")
+	}
+
+	if _, err := decompress(99, []byte("{}")); err == nil {
+		t.Fatalf("decompress with unknown type succeeded")
+	}
+}
+
+func TestGzipDecompressPlainBody(t *testing.T) {
+	// 服务端未压缩时原样返回
+	got, err := decompress(CompressTypeGzip, []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("decompress error: %v", err)
+	}
+
+	if string(got) != `{"a":1}` {
+		t.Fatalf("decompress plain body got %s", got)
+	}
+}
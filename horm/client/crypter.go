@@ -0,0 +1,206 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/horm-database/common/codec"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+func init() {
+	RegisterCrypter(CrypterAES256GCM, NewAEADCrypter("aes-256-gcm", 32, newAESGCM))
+	RegisterCrypter(CrypterChaCha20Poly1305, NewAEADCrypter("chacha20-poly1305",
+		chacha20poly1305.KeySize, chacha20poly1305.New))
+}
+
+// 内置帧加密算法 id，0 表示使用 common/codec 默认的加密帧
+const (
+	CrypterDefault          int8 = 0 // common/codec 默认加密
+	CrypterAES256GCM        int8 = 1 // AES-256-GCM
+	CrypterChaCha20Poly1305 int8 = 2 // ChaCha20-Poly1305
+)
+
+// 可插拔加密帧头，TotalLen 与普通帧头处于同一偏移，保证 Framer 可以直接读取加密的返回帧。
+// 格式：FrameType(1) Version(1) CrypterID(1) ProtocolType(1) TotalLen(4) WorkspaceID(4)
+const (
+	cryptFrameHeadLen = 12 // total length of crypt frame head
+	cryptFrameVersion = 2  // version of crypt frame, 1 is used by common/codec encrypt frame
+)
+
+// FrameCrypter 帧加解密算法，加解密密钥由 workspace token 派生。
+type FrameCrypter interface {
+	// Encrypt 加密帧体，additionalData 为需要校验但不加密的帧头。
+	Encrypt(workspaceID int, token, plaintext, additionalData []byte) ([]byte, error)
+	// Decrypt 解密帧体，additionalData 为需要校验但不加密的帧头。
+	Decrypt(workspaceID int, token, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+var (
+	crypters = make(map[int8]FrameCrypter)
+)
+
+// RegisterCrypter registers a FrameCrypter by id.
+func RegisterCrypter(id int8, c FrameCrypter) {
+	crypters[id] = c
+}
+
+// GetCrypter gets a FrameCrypter by id.
+func GetCrypter(id int8) FrameCrypter {
+	c := crypters[id]
+	return c
+}
+
+// aeadCrypter 基于 AEAD 的帧加密算法，密钥由 HKDF-SHA256 从 workspace token 派生。
+type aeadCrypter struct {
+	name    string
+	keySize int
+	newAEAD func(key []byte) (cipher.AEAD, error)
+}
+
+// NewAEADCrypter creates a FrameCrypter based on AEAD cipher.
+// param: name 算法名称，作为 HKDF 的 info，不同算法派生出不同的密钥
+// param: keySize 密钥长度
+// param: newAEAD 根据密钥创建 AEAD
+func NewAEADCrypter(name string, keySize int, newAEAD func(key []byte) (cipher.AEAD, error)) FrameCrypter {
+	return &aeadCrypter{
+		name:    name,
+		keySize: keySize,
+		newAEAD: newAEAD,
+	}
+}
+
+// Encrypt implements FrameCrypter.Encrypt, the output is nonce + ciphertext.
+func (c *aeadCrypter) Encrypt(workspaceID int, token, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := c.aead(workspaceID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt implements FrameCrypter.Decrypt, the input is nonce + ciphertext.
+func (c *aeadCrypter) Decrypt(workspaceID int, token, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := c.aead(workspaceID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%s ciphertext too short", c.name)
+	}
+
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+}
+
+func (c *aeadCrypter) aead(workspaceID int, token []byte) (cipher.AEAD, error) {
+	if len(token) == 0 {
+		return nil, fmt.Errorf("%s token is empty", c.name)
+	}
+
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, uint32(workspaceID))
+
+	key := make([]byte, c.keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, token, salt, []byte(c.name)), key); err != nil {
+		return nil, err
+	}
+
+	return c.newAEAD(key)
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptFrame 用指定算法加密整个帧
+func encryptFrame(reqParam *ReqParam, frame []byte) ([]byte, error) {
+	crypter := GetCrypter(reqParam.Crypter)
+	if crypter == nil {
+		return nil, fmt.Errorf("frame crypter %d not exist", reqParam.Crypter)
+	}
+
+	head := make([]byte, cryptFrameHeadLen)
+	head[0] = codec.FrameTypeEncrypt
+	head[1] = cryptFrameVersion
+	head[2] = byte(reqParam.Crypter)
+	head[3] = codec.ProtocolTypeRPC
+	binary.BigEndian.PutUint32(head[8:12], uint32(reqParam.WorkspaceID))
+
+	// 密文长度在加密前无法确定，TotalLen 不参与认证
+	body, err := crypter.Encrypt(reqParam.WorkspaceID, []byte(reqParam.Token), frame, head)
+	if err != nil {
+		return nil, err
+	}
+
+	totalLen := int64(cryptFrameHeadLen) + int64(len(body))
+	if totalLen > int64(codec.MaxFrameSize) {
+		return nil, codec.ErrFrameTooLarge
+	}
+
+	binary.BigEndian.PutUint32(head[4:8], uint32(totalLen))
+	return append(head, body...), nil
+}
+
+// isCryptFrame 是否是可插拔算法加密帧
+func isCryptFrame(buf []byte) bool {
+	return len(buf) >= cryptFrameHeadLen &&
+		buf[0] == codec.FrameTypeEncrypt && buf[1] == cryptFrameVersion
+}
+
+// decryptFrame 解密加密帧，返回普通帧
+func decryptFrame(reqParam *ReqParam, buf []byte) ([]byte, error) {
+	if binary.BigEndian.Uint32(buf[4:8]) != uint32(len(buf)) {
+		return nil, errors.New("crypt frame total len is not actual buf len")
+	}
+
+	crypterID := int8(buf[2])
+	crypter := GetCrypter(crypterID)
+	if crypter == nil {
+		return nil, fmt.Errorf("frame crypter %d not exist", crypterID)
+	}
+
+	workspaceID := int(binary.BigEndian.Uint32(buf[8:12]))
+	if workspaceID != reqParam.WorkspaceID {
+		return nil, fmt.Errorf("crypt frame workspace %d is not request workspace %d",
+			workspaceID, reqParam.WorkspaceID)
+	}
+
+	head := make([]byte, cryptFrameHeadLen)
+	copy(head, buf[:cryptFrameHeadLen])
+	binary.BigEndian.PutUint32(head[4:8], 0)
+
+	return crypter.Decrypt(workspaceID, []byte(reqParam.Token), buf[cryptFrameHeadLen:], head)
+}
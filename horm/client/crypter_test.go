@@ -0,0 +1,159 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestCryptFrameRoundTrip(t *testing.T) {
+	frame := bytes.Repeat([]byte("horm frame body "), 64)
+
+	tests := []struct {
+		name    string
+		crypter int8
+	}{
+		{"aes-256-gcm", CrypterAES256GCM},
+		{"chacha20-poly1305", CrypterChaCha20Poly1305},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqParam := &ReqParam{WorkspaceID: 31, Crypter: tt.crypter, Token: "workspace-token"}
+
+			buf, err := encryptFrame(reqParam, frame)
+			if err != nil {
+				t.Fatalf("encryptFrame error: %v", err)
+			}
+
+			if !isCryptFrame(buf) {
+				t.Fatalf("encrypted buf is not crypt frame")
+			}
+
+			if bytes.Contains(buf, []byte("horm frame body")) {
+				t.Fatalf("encrypted buf contains plaintext")
+			}
+
+			if int(binary.BigEndian.Uint32(buf[4:8])) != len(buf) {
+				t.Fatalf("total len %d, want %d", binary.BigEndian.Uint32(buf[4:8]), len(buf))
+			}
+
+			got, err := decryptFrame(reqParam, buf)
+			if err != nil {
+				t.Fatalf("decryptFrame error: %v", err)
+			}
+
+			if !bytes.Equal(got, frame) {
+				t.Fatalf("decrypted frame not equal to plaintext")
+			}
+		})
+	}
+}
+
+func TestCryptFrameReject(t *testing.T) {
+	reqParam := &ReqParam{WorkspaceID: 31, Crypter: CrypterAES256GCM, Token: "workspace-token"}
+
+	tests := []struct {
+		name   string
+		param  *ReqParam
+		modify func(buf []byte) []byte
+	}{
+		{
+			name:  "tamper body",
+			param: reqParam,
+			modify: func(buf []byte) []byte {
+				buf[len(buf)-1] ^= 0x01
+				return buf
+			},
+		},
+		{
+			name:  "tamper head",
+			param: reqParam,
+			modify: func(buf []byte) []byte {
+				buf[3] ^= 0x01
+				return buf
+			},
+		},
+		{
+			name:  "tamper crypter id",
+			param: reqParam,
+			modify: func(buf []byte) []byte {
+				buf[2] = byte(CrypterChaCha20Poly1305)
+				return buf
+			},
+		},
+		{
+			name:  "truncated",
+			param: reqParam,
+			modify: func(buf []byte) []byte {
+				buf = buf[:len(buf)-4]
+				binary.BigEndian.PutUint32(buf[4:8], uint32(len(buf)))
+				return buf
+			},
+		},
+		{
+			name:   "wrong token",
+			param:  &ReqParam{WorkspaceID: 31, Crypter: CrypterAES256GCM, Token: "other-token"},
+			modify: func(buf []byte) []byte { return buf },
+		},
+		{
+			name:   "wrong workspace",
+			param:  &ReqParam{WorkspaceID: 32, Crypter: CrypterAES256GCM, Token: "workspace-token"},
+			modify: func(buf []byte) []byte { return buf },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := encryptFrame(reqParam, []byte("horm frame body"))
+			if err != nil {
+				t.Fatalf("encryptFrame error: %v", err)
+			}
+
+			if _, err = decryptFrame(tt.param, tt.modify(buf)); err == nil {
+				t.Fatalf("decryptFrame succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestAEADCrypterKeyDerivation(t *testing.T) {
+	aes := GetCrypter(CrypterAES256GCM)
+	chacha := GetCrypter(CrypterChaCha20Poly1305)
+
+	ciphertext, err := aes.Encrypt(31, []byte("token"), []byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	if _, err = aes.Decrypt(32, []byte("token"), ciphertext, nil); err == nil {
+		t.Fatalf("key of other workspace decrypted ciphertext")
+	}
+
+	if _, err = aes.Encrypt(31, nil, []byte("plaintext"), nil); err == nil {
+		t.Fatalf("Encrypt with empty token succeeded")
+	}
+
+	other, err := chacha.Encrypt(31, []byte("token"), []byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+
+	if _, err = aes.Decrypt(31, []byte("token"), other, nil); err == nil {
+		t.Fatalf("ciphertext of other crypter decrypted")
+	}
+}
@@ -39,6 +39,8 @@ type Options struct {
 
 	Node *onceNode // for getting node info
 
+	Timing *Timing // for getting timing breakdown
+
 	// transport info
 	Transport *transport
 	Address   string     // IP:Port. Note: address has been resolved from naming service.
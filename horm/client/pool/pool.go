@@ -21,6 +21,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -124,6 +125,8 @@ func (p *Pool) GetConn(ctx context.Context, network string, address string) (*Po
 	}
 
 	newPool := &ConnectionPool{
+		network:         network,
+		address:         address,
 		Dial:            p.getDialFunc(network, address),
 		MinIdle:         p.opts.MinIdle,
 		MaxIdle:         p.opts.MaxIdle,
@@ -167,6 +170,50 @@ type ConnectionPool struct {
 	once        sync.Once     // indicates whether ch has been initialized.
 	idle        connList      // idle connection list.
 	forceClosed bool          // force close the connection, suitable for streaming scenarios.
+
+	network      string // network of backend node.
+	address      string // address of backend node.
+	waiting      int64  // current number of requests waiting for a connection.
+	waitCount    int64  // total number of waits for a connection.
+	waitDuration int64  // total nanoseconds spent waiting for a connection.
+}
+
+// Stat is the statistics of a connection pool.
+type Stat struct {
+	Network      string        // network of backend node.
+	Address      string        // address of backend node.
+	Active       int           // current number of active connections.
+	Idle         int           // current number of idle connections.
+	Waiting      int64         // current number of requests waiting for a connection.
+	WaitCount    int64         // total number of waits for a connection.
+	WaitDuration time.Duration // total time spent waiting for a connection.
+}
+
+// Stats gets the statistics of all connection pools.
+func (p *Pool) Stats() []*Stat {
+	var stats []*Stat
+	p.connectionPools.Range(func(_, v interface{}) bool {
+		stats = append(stats, v.(*ConnectionPool).Stat())
+		return true
+	})
+	return stats
+}
+
+// Stat gets the statistics of the connection pool.
+func (p *ConnectionPool) Stat() *Stat {
+	p.mu.Lock()
+	active, idle := p.active, p.idle.count
+	p.mu.Unlock()
+
+	return &Stat{
+		Network:      p.network,
+		Address:      p.address,
+		Active:       active,
+		Idle:         idle,
+		Waiting:      atomic.LoadInt64(&p.waiting),
+		WaitCount:    atomic.LoadInt64(&p.waitCount),
+		WaitDuration: time.Duration(atomic.LoadInt64(&p.waitDuration)),
+	}
 }
 
 func (p *ConnectionPool) initialConnections(count int) {
@@ -257,15 +304,28 @@ func (p *ConnectionPool) initializeCh() {
 func (p *ConnectionPool) get(ctx context.Context, forceNew bool) (*PoolConn, error) {
 	if p.Wait && p.MaxActive > 0 {
 		p.initializeCh()
+
+		begin := time.Now()
+		atomic.AddInt64(&p.waiting, 1)
+		atomic.AddInt64(&p.waitCount, 1)
+
+		var err error
 		if ctx == nil {
 			<-p.ch
 		} else {
 			select {
 			case <-p.ch:
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				err = ctx.Err()
 			}
 		}
+
+		atomic.AddInt64(&p.waiting, -1)
+		atomic.AddInt64(&p.waitDuration, int64(time.Since(begin)))
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if !forceNew {
@@ -476,6 +536,7 @@ type PoolConn struct {
 	fr         *codec.Framer
 	t          time.Time
 	created    time.Time
+	readAt     time.Time // 写请求后首次读到数据的时间
 	next, prev *PoolConn
 	pool       *ConnectionPool
 	closed     bool
@@ -529,6 +590,7 @@ func (pc *PoolConn) Write(b []byte) (int, error) {
 	if pc.closed {
 		return 0, ErrConnClosed
 	}
+	pc.readAt = time.Time{}
 	n, err := pc.Conn.Write(b)
 	if err != nil {
 		pc.pool.put(pc, true)
@@ -542,12 +604,20 @@ func (pc *PoolConn) Read(b []byte) (int, error) {
 		return 0, ErrConnClosed
 	}
 	n, err := pc.Conn.Read(b)
+	if n > 0 && pc.readAt.IsZero() {
+		pc.readAt = time.Now()
+	}
 	if err != nil {
 		pc.pool.put(pc, true)
 	}
 	return n, err
 }
 
+// FirstReadTime gets the time when the first byte is read after last write.
+func (pc *PoolConn) FirstReadTime() time.Time {
+	return pc.readAt
+}
+
 // Close overrides the Close method of net.Conn and puts it back into the connection pool.
 func (pc *PoolConn) Close() error {
 	if pc.closed {
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"sync"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/naming"
+	"github.com/horm-database/go-horm/horm/client/pool"
+	"github.com/horm-database/go-horm/horm/stats"
+)
+
+func init() {
+	stats.RegisterCollector(collectPoolStats)
+}
+
+// poolWaits 上次采集时各连接池的累计等待次数、等待耗时，用于计算计数器增量
+var poolWaits = struct {
+	sync.Mutex
+	last map[string]poolWait
+}{last: map[string]poolWait{}}
+
+type poolWait struct {
+	count   float64
+	seconds float64
+}
+
+// collectPoolStats 采集连接池状态，连接数为仪表盘，累计等待次数、等待耗时以增量累加到计数器
+func collectPoolStats(r stats.Registry) {
+	reportPoolStats(r, pool.DefaultConnectionPool.Stats())
+}
+
+func reportPoolStats(r stats.Registry, poolStats []*pool.Stat) {
+	poolWaits.Lock()
+	defer poolWaits.Unlock()
+
+	for _, s := range poolStats {
+		labels := stats.Labels{"network": s.Network, "address": s.Address}
+		r.SetGauge(stats.PoolActiveConns, labels, float64(s.Active))
+		r.SetGauge(stats.PoolIdleConns, labels, float64(s.Idle))
+		r.SetGauge(stats.PoolWaitingRequests, labels, float64(s.Waiting))
+
+		cur := poolWait{count: float64(s.WaitCount), seconds: s.WaitDuration.Seconds()}
+		key := s.Network + "|" + s.Address
+		last := poolWaits.last[key]
+		if cur.count < last.count || cur.seconds < last.seconds { // 连接池重建，累计值从 0 开始
+			last = poolWait{}
+		}
+		poolWaits.last[key] = cur
+
+		r.AddCounter(stats.PoolWaitCount, labels, cur.count-last.count)
+		r.AddCounter(stats.PoolWaitSeconds, labels, cur.seconds-last.seconds)
+	}
+}
+
+// reportNode 上报节点请求结果，连接失败、超时、网络错误视为节点不健康
+func reportNode(node *naming.Node, err error) {
+	labels := stats.Labels{"node": node.Address, "result": "success"}
+	healthy := 1.0
+
+	if err != nil {
+		labels["result"] = "fail"
+		if e, ok := err.(*errs.Error); ok && e.Type == errs.ETypeSystem && (e.Code == errs.ErrClientConnect ||
+			e.Code == errs.ErrClientTimeout || e.Code == errs.ErrClientNet || e.Code == errs.ErrClientReadFrame) {
+			healthy = 0
+		}
+	}
+
+	stats.AddCounter(stats.NodeRequestTotal, labels, 1)
+	stats.SetGauge(stats.NodeHealthy, stats.Labels{"node": node.Address}, healthy)
+}
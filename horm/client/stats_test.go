@@ -0,0 +1,63 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horm-database/go-horm/horm/client/pool"
+	"github.com/horm-database/go-horm/horm/stats"
+)
+
+func TestReportPoolStats(t *testing.T) {
+	r := stats.NewRegistry()
+	stat := func(count int64, wait time.Duration) []*pool.Stat {
+		return []*pool.Stat{{Network: "tcp", Address: "10.0.0.1:8180", Active: 2, Idle: 1, WaitCount: count, WaitDuration: wait}}
+	}
+
+	tests := []struct {
+		name    string
+		stat    []*pool.Stat
+		waits   string
+		seconds string
+	}{
+		{"first collect", stat(3, time.Second), "3", "1"},
+		{"no new waits", stat(3, time.Second), "3", "1"},
+		{"new waits", stat(5, 1500*time.Millisecond), "5", "1.5"},
+		{"pool recreated", stat(1, 250*time.Millisecond), "6", "1.75"},
+	}
+
+	labels := `{address="10.0.0.1:8180",network="tcp"} `
+	for _, tt := range tests {
+		reportPoolStats(r, tt.stat)
+
+		var buf bytes.Buffer
+		_ = r.WriteText(&buf)
+		out := buf.String()
+
+		for _, want := range []string{
+			"# TYPE " + stats.PoolWaitCount + " counter\n" + stats.PoolWaitCount + labels + tt.waits + "\n",
+			"# TYPE " + stats.PoolWaitSeconds + " counter\n" + stats.PoolWaitSeconds + labels + tt.seconds + "\n",
+			"# TYPE " + stats.PoolActiveConns + " gauge\n" + stats.PoolActiveConns + labels + "2\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("%s: output missing %q in:\n%s", tt.name, want, out)
+			}
+		}
+	}
+}
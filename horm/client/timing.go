@@ -0,0 +1,37 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timing 单次请求耗时明细
+type Timing struct {
+	Select time.Duration // 选址耗时
+	Dial   time.Duration // 建立连接或等待连接池空闲连接耗时
+	Write  time.Duration // 写请求帧耗时
+	Server time.Duration // 写完请求到收到返回首字节的耗时，包括服务端处理与网络传输
+	Read   time.Duration // 收到首字节到读完返回帧的耗时
+	Decode time.Duration // 返回帧解码耗时，包括解密、解压
+	Total  time.Duration // 总耗时
+}
+
+// String 耗时明细
+func (t *Timing) String() string {
+	return fmt.Sprintf("select:%s, dial:%s, write:%s, server:%s, read:%s, decode:%s, total:%s",
+		t.Select, t.Dial, t.Write, t.Server, t.Read, t.Decode, t.Total)
+}
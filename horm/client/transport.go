@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/horm-database/common/codec"
 	"github.com/horm-database/common/errs"
@@ -50,7 +51,14 @@ func (c *transport) tcpRoundTrip(ctx context.Context, reqData []byte, opts *Opti
 		return nil, errs.New(errs.ErrClientConnect, "tcp transport: connection pool empty")
 	}
 
-	tcpConn, err := c.dialTCP(ctx, opts)
+	dialBegin := time.Now()
+	dialCtx, span := startSpan(ctx, "pool.get")
+	tcpConn, err := c.dialTCP(dialCtx, opts)
+	endSpan(span, err)
+	if opts.Timing != nil {
+		opts.Timing.Dial = time.Since(dialBegin)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -70,10 +78,27 @@ func (c *transport) tcpRoundTrip(ctx context.Context, reqData []byte, opts *Opti
 		return nil, errs.New(errs.ErrClientTimeout, "tcp transport timeout before Write: "+ctx.Err().Error())
 	}
 
+	writeBegin := time.Now()
 	if err := c.tcpWriteFrame(tcpConn, reqData); err != nil {
 		return nil, err
 	}
-	return c.tcpReadFrame(tcpConn)
+	writeEnd := time.Now()
+
+	rspData, err := c.tcpReadFrame(tcpConn)
+
+	if opts.Timing != nil {
+		readEnd := time.Now()
+		firstRead := tcpConn.FirstReadTime()
+		if firstRead.IsZero() {
+			firstRead = readEnd
+		}
+
+		opts.Timing.Write = writeEnd.Sub(writeBegin)
+		opts.Timing.Server = firstRead.Sub(writeEnd)
+		opts.Timing.Read = readEnd.Sub(firstRead)
+	}
+
+	return rspData, err
 }
 
 // dialTCP establishes a TCP connection.
@@ -0,0 +1,196 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/types"
+)
+
+// hasUnitReceiver 复合查询是否有执行单元通过 WithReceiver 接收结果
+func hasUnitReceiver(q *Query) bool {
+	var has bool
+	walkQuery(q, func(s *Query) {
+		if len(s.Receiver) > 0 || s.IsNil != nil || s.RespError != nil {
+			has = true
+		}
+	})
+	return has
+}
+
+// decodeComp 解码复合查询结果到各执行单元 WithReceiver 设置的接收者
+func (c *call) decodeComp(head *Query, result []byte) error {
+	rets := map[string]interface{}{}
+	err := json.Api.Unmarshal(result, &rets)
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode, "[request_id=%d] "+
+			"result decode to unit receiver error: %v, resp=[%s]", head.RequestID, err, string(result))
+	}
+
+	c.decodeCompUnits(head, head, rets, false)
+	return nil
+}
+
+// decodeCompUnits 解码同一层级所有执行单元的结果
+// param: rets 执行单元 key 与结果的映射，顶层为整个返回，子查询为父查询的单条返回数据
+// param: multi 是否为父查询返回数组中的一个元素，此时子查询每个结果都会依次追加到接收者切片，
+// 与父查询返回数组一一对应，空返回或错误时追加零值。
+func (c *call) decodeCompUnits(head, first *Query, rets map[string]interface{}, multi bool) {
+	for q := first; q != nil; q = q.next {
+		c.decodeCompUnit(head, q, rets[q.Key], multi)
+	}
+}
+
+// decodeCompUnit 解码单个执行单元的结果，结果结构参见 proto.CompResult
+func (c *call) decodeCompUnit(head, q *Query, ret interface{}, multi bool) {
+	m, _ := ret.(map[string]interface{})
+
+	var err error
+	if e := compError(m["error"]); e != nil {
+		err = e
+	}
+
+	isNil := types.InterfaceToBool(m["is_nil"])
+	data, hasData := m["data"]
+
+	switch {
+	case err != nil || isNil || !hasData:
+		if multi {
+			appendZero(q.Receiver)
+		}
+	case multi:
+		err = appendDecode(q, data)
+	default:
+		err = q.GetCoder().Decode(q.ResultType, data, q.Receiver)
+	}
+
+	if err != nil {
+		if _, ok := err.(*errs.Error); !ok {
+			err = errs.Newf(errs.ErrClientDecode, "[request_id=%d] %v, result=[%s]",
+				head.RequestID, err, types.ToString(data))
+		}
+		c.compUnitError(head, q, err)
+	}
+
+	c.compNil(q, isNil || (err == nil && !hasData))
+
+	if q.sub == nil || err != nil || isNil {
+		return
+	}
+
+	switch v := data.(type) {
+	case map[string]interface{}:
+		c.decodeCompUnits(head, q.sub, v, multi)
+	case []interface{}:
+		for _, elem := range v {
+			elemMap, _ := elem.(map[string]interface{})
+			c.decodeCompUnits(head, q.sub, elemMap, true)
+		}
+	}
+}
+
+// compUnitError 记录执行单元错误，父查询返回数组时仅记录第一个错误
+func (c *call) compUnitError(head, q *Query, err error) {
+	if c.unitErrs[q] != nil {
+		return
+	}
+
+	err = wrapError(err, q.Unit.Name, head)
+	c.unitError(q, err)
+	if q.RespError != nil {
+		*q.RespError = err
+	}
+}
+
+// compNil 设置执行单元是否为空，父查询返回数组时，所有结果都为空才为空
+func (c *call) compNil(q *Query, isNil bool) {
+	if q.IsNil == nil {
+		return
+	}
+
+	if c.compNotNil == nil {
+		c.compNotNil = map[*Query]bool{}
+	}
+
+	if !isNil {
+		c.compNotNil[q] = true
+		*q.IsNil = false
+	} else if !c.compNotNil[q] {
+		*q.IsNil = true
+	}
+}
+
+// compError 解析执行单元返回的错误
+func compError(v interface{}) *errs.Error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	code, _ := types.InterfaceToInt(m["code"])
+	if code == 0 {
+		return nil
+	}
+
+	typ, _ := types.InterfaceToInt(m["type"])
+
+	return &errs.Error{
+		Type: errs.EType(typ),
+		Code: code,
+		Msg:  types.InterfaceToString(m["msg"]),
+		Sql:  types.InterfaceToString(m["sql"]),
+	}
+}
+
+// appendDecode 将结果解码为接收者切片的新元素并追加
+func appendDecode(q *Query, data interface{}) error {
+	for _, receiver := range q.Receiver {
+		sv, err := sliceValue(receiver)
+		if err != nil {
+			return err
+		}
+
+		elem := reflect.New(sv.Type().Elem())
+		err = q.GetCoder().Decode(q.ResultType, data, []interface{}{elem.Interface()})
+		if err != nil {
+			return err
+		}
+
+		sv.Set(reflect.Append(sv, elem.Elem()))
+	}
+
+	return nil
+}
+
+// appendZero 接收者切片追加零值
+func appendZero(receivers []interface{}) {
+	for _, receiver := range receivers {
+		if sv, err := sliceValue(receiver); err == nil {
+			sv.Set(reflect.Append(sv, reflect.Zero(sv.Type().Elem())))
+		}
+	}
+}
+
+func sliceValue(receiver interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(receiver)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, fmt.Errorf("receiver of sub query under array result must be a pointer to slice, got %T", receiver)
+	}
+	return rv.Elem(), nil
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+)
+
+func TestDecodeCompNil(t *testing.T) {
+	var studentNil, courseNil = true, false
+	var student, course map[string]interface{}
+
+	head := NewQuery("student").Find(Where{"id": 1}).WithReceiver(&studentNil, nil, &student)
+	head.Next("course").Find(Where{"id": 2}).WithReceiver(&courseNil, nil, &course)
+
+	c := &call{}
+	err := c.decodeComp(head, []byte(`{"student":{"data":{"id":1,"name":"jerry"}},"course":{"is_nil":true}}`))
+	if err != nil {
+		t.Fatalf("decode error %v", err)
+	}
+
+	if studentNil || fmt.Sprint(student["name"]) != "jerry" {
+		t.Fatalf("student isNil %v result %v", studentNil, student)
+	}
+
+	if !courseNil || course != nil {
+		t.Fatalf("course isNil %v result %v", courseNil, course)
+	}
+
+	// 缺失的执行单元同样视为空
+	courseNil = false
+	err = (&call{}).decodeComp(head, []byte(`{"student":{"data":{"id":1}}}`))
+	if err != nil || !courseNil {
+		t.Fatalf("missing unit, isNil %v error %v", courseNil, err)
+	}
+}
+
+func TestDecodeCompArray(t *testing.T) {
+	var students []map[string]interface{}
+	var courses []map[string]interface{}
+	var courseNil bool
+	var courseErr error
+
+	head := NewQuery("student").FindAll().WithReceiver(nil, nil, &students)
+	head.AddSub(NewQuery("course").Find(Where{"@student_id": "/student.id"}).
+		WithReceiver(&courseNil, &courseErr, &courses))
+
+	c := &call{}
+	err := c.decodeComp(head, []byte(`{"student":{"data":[`+
+		`{"id":1,"course":{"data":{"name":"math"}}},`+
+		`{"id":2,"course":{"is_nil":true}},`+
+		`{"id":3,"course":{"data":{"name":"art"}}}]}}`))
+	if err != nil {
+		t.Fatalf("decode error %v", err)
+	}
+
+	if len(students) != 3 || len(courses) != 3 {
+		t.Fatalf("students %v courses %v", students, courses)
+	}
+
+	if fmt.Sprint(courses[0]["name"]) != "math" || courses[1] != nil || fmt.Sprint(courses[2]["name"]) != "art" {
+		t.Fatalf("courses not aligned with students: %v", courses)
+	}
+
+	if courseNil || courseErr != nil {
+		t.Fatalf("course isNil %v error %v", courseNil, courseErr)
+	}
+
+	// 所有元素为空时才为空
+	courses = nil
+	err = (&call{}).decodeComp(head, []byte(`{"student":{"data":[{"id":1},{"id":2,"course":{"is_nil":true}}]}}`))
+	if err != nil || !courseNil || len(courses) != 2 {
+		t.Fatalf("all nil, isNil %v courses %v error %v", courseNil, courses, err)
+	}
+}
+
+func TestDecodeCompUnitError(t *testing.T) {
+	var courses []map[string]interface{}
+	var teacher map[string]interface{}
+	var courseErr, teacherErr error
+
+	head := NewQuery("student").FindAll()
+	head.AddSub(NewQuery("course").Find().WithReceiver(nil, &courseErr, &courses))
+	head.Next("teacher").Find().WithReceiver(nil, &teacherErr, &teacher)
+
+	c := &call{}
+	err := c.decodeComp(head, []byte(`{"student":{"data":[`+
+		`{"id":1,"course":{"error":{"code":1001,"msg":"first failed"}}},`+
+		`{"id":2,"course":{"data":{"name":"math"}}},`+
+		`{"id":3,"course":{"error":{"code":1002,"msg":"second failed"}}}]},`+
+		`"teacher":{"data":{"name":"tom"}}}`))
+	if err != nil {
+		t.Fatalf("decode error %v", err)
+	}
+
+	if courseErr == nil || queryErrCode(courseErr, "course") != 1001 || c.unitErrs[head.sub] != courseErr {
+		t.Fatalf("course error %v, unit errors %v", courseErr, c.unitErrs)
+	}
+
+	if len(courses) != 3 || courses[0] != nil || fmt.Sprint(courses[1]["name"]) != "math" || courses[2] != nil {
+		t.Fatalf("courses %v", courses)
+	}
+
+	// 其他执行单元不受影响
+	if teacherErr != nil || fmt.Sprint(teacher["name"]) != "tom" || len(c.unitErrs) != 1 {
+		t.Fatalf("teacher %v error %v, unit errors %v", teacher, teacherErr, c.unitErrs)
+	}
+}
+
+func TestDecodeCompTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		unit   string
+		build  func(err *error) *Query
+		result string
+	}{
+		{
+			name: "non slice receiver under array",
+			unit: "course",
+			build: func(err *error) *Query {
+				var course map[string]interface{}
+				head := NewQuery("student").FindAll()
+				head.AddSub(NewQuery("course").Find().WithReceiver(nil, err, &course))
+				return head
+			},
+			result: `{"student":{"data":[{"id":1,"course":{"data":{"name":"math"}}}]}}`,
+		},
+		{
+			name: "undecodable data",
+			unit: "student",
+			build: func(err *error) *Query {
+				var id int
+				return NewQuery("student").Find().WithReceiver(nil, err, &id)
+			},
+			result: `{"student":{"data":{"id":1}}}`,
+		},
+		{
+			name: "undecodable element",
+			unit: "course",
+			build: func(err *error) *Query {
+				var ids []int
+				head := NewQuery("student").FindAll()
+				head.AddSub(NewQuery("course").Find().WithReceiver(nil, err, &ids))
+				return head
+			},
+			result: `{"student":{"data":[{"id":1,"course":{"data":{"name":"math"}}}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unitErr error
+			head := tt.build(&unitErr)
+
+			c := &call{}
+			if err := c.decodeComp(head, []byte(tt.result)); err != nil {
+				t.Fatalf("decode error %v", err)
+			}
+
+			if queryErrCode(unitErr, tt.unit) != errs.ErrClientDecode || len(c.unitErrs) != 1 {
+				t.Fatalf("want decode error, got %v, unit errors %v", unitErr, c.unitErrs)
+			}
+		})
+	}
+
+	// 返回结果不是合法 json
+	err := (&call{}).decodeComp(NewQuery("student").Find(), []byte(`{"student":`))
+	if errs.Code(err) != errs.ErrClientDecode {
+		t.Fatalf("want decode error, got %v", err)
+	}
+}
+
+// queryErrCode 返回执行单元错误的错误码，单元名不匹配时返回 -1
+func queryErrCode(err error, unit string) int {
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Unit != unit {
+		return -1
+	}
+	return qe.Code
+}
@@ -0,0 +1,448 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+)
+
+// structured 结构化查询操作，其余操作视为 redis 命令
+var structured = map[string]bool{
+	consts.OpInsert:      true,
+	consts.OpReplace:     true,
+	consts.OpUpdate:      true,
+	consts.OpDelete:      true,
+	consts.OpFind:        true,
+	consts.OpFindAll:     true,
+	consts.OpTransaction: true,
+	consts.OpCreate:      true,
+	consts.OpDrop:        true,
+}
+
+// String 同 Debug
+func (s *Query) String() string {
+	return s.Debug()
+}
+
+// Debug 不执行查询，渲染整个查询语句（包括并行查询、子查询、事务），
+// 包含格式化的请求 json，以及 sql、redis 语句的近似表示，例如 SELECT * FROM student WHERE identify = ?，
+// 在语句副本上渲染，不会修改原语句
+func (s *Query) Debug() string {
+	units, err := createUnits(s.cloneTree())
+	if err != nil {
+		return fmt.Sprintf("query error: %v", err)
+	}
+
+	buf, err := json.Api.MarshalIndent(units, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("query marshal error: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.Write(buf)
+	sb.WriteString("\n")
+
+	for _, unit := range units {
+		writeStatement(&sb, unit, 0)
+	}
+
+	return sb.String()
+}
+
+func writeStatement(sb *strings.Builder, unit *proto.Unit, depth int) {
+	statement, args := Statement(unit)
+
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString("-- ")
+	sb.WriteString(unit.Name)
+	sb.WriteString(": ")
+	sb.WriteString(statement)
+	if len(args) > 0 {
+		sb.WriteString(fmt.Sprintf(" %v", args))
+	}
+	sb.WriteString("\n")
+
+	for _, sub := range unit.Sub {
+		writeStatement(sb, sub, depth+1)
+	}
+
+	for _, trans := range unit.Trans {
+		writeStatement(sb, trans, depth+1)
+	}
+}
+
+// Statement 执行单元的 sql、redis 语句近似表示，仅用于调试，并不是服务端实际执行的语句。
+// sql 参数以 ? 占位，参数值按顺序返回。
+func Statement(unit *proto.Unit) (string, []interface{}) {
+	if unit.Query != "" {
+		return unit.Query, unit.Args
+	}
+
+	if !structured[unit.Op] {
+		return redisStatement(unit), nil
+	}
+
+	return sqlStatement(unit)
+}
+
+func sqlStatement(unit *proto.Unit) (string, []interface{}) {
+	var sb strings.Builder
+	var args []interface{}
+
+	table := unit.Name
+	if len(unit.Shard) > 0 {
+		table = strings.Join(unit.Shard, ", ")
+	}
+
+	switch unit.Op {
+	case consts.OpFind, consts.OpFindAll:
+		columns := "*"
+		if len(unit.Column) > 0 {
+			columns = strings.Join(unit.Column, ", ")
+		}
+
+		sb.WriteString("SELECT " + columns + " FROM " + table)
+		for _, join := range unit.Join {
+			sb.WriteString(" " + strings.ToUpper(join.Type) + " JOIN " + join.Table)
+			if len(join.Using) > 0 {
+				sb.WriteString(" USING (" + strings.Join(join.Using, ", ") + ")")
+			} else if len(join.On) > 0 {
+				sb.WriteString(" ON " + joinOn(join.On))
+			}
+		}
+
+		args = writeWhere(&sb, " WHERE ", unit.Where, args)
+
+		if len(unit.Group) > 0 {
+			sb.WriteString(" GROUP BY " + strings.Join(unit.Group, ", "))
+		}
+
+		args = writeWhere(&sb, " HAVING ", unit.Having, args)
+
+		if len(unit.Order) > 0 {
+			sb.WriteString(" ORDER BY " + orderBy(unit.Order))
+		}
+
+		if unit.Op == consts.OpFind {
+			sb.WriteString(" LIMIT 1")
+		} else if unit.Page > 0 && unit.Size > 0 {
+			sb.WriteString(fmt.Sprintf(" LIMIT %d, %d", (unit.Page-1)*unit.Size, unit.Size))
+		} else if unit.Size > 0 {
+			sb.WriteString(fmt.Sprintf(" LIMIT %d, %d", unit.From, unit.Size))
+		}
+
+		if lock, ok := unit.Params[paramLock].(string); ok {
+			sb.WriteString(" FOR " + strings.ToUpper(lock))
+			if wait, ok := unit.Params[paramLockWait].(string); ok {
+				sb.WriteString(" " + strings.ToUpper(strings.ReplaceAll(wait, "_", " ")))
+			}
+		}
+	case consts.OpInsert, consts.OpReplace:
+		datas := unit.Datas
+		if len(unit.Data) > 0 {
+			datas = []map[string]interface{}{unit.Data}
+		}
+
+		var columns []string
+		if len(datas) > 0 {
+			columns = sortedKeys(datas[0])
+		}
+
+		placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		values := make([]string, len(datas))
+		for i, data := range datas {
+			values[i] = placeholders
+			for _, column := range columns {
+				args = append(args, data[column])
+			}
+		}
+
+		op := strings.ToUpper(unit.Op)
+		if unit.Params[paramOnConflict] == conflictIgnore {
+			op += " IGNORE"
+		}
+
+		sb.WriteString(op + " INTO " + table +
+			" (" + strings.Join(columns, ", ") + ") VALUES " + strings.Join(values, ", "))
+
+		if updates, ok := unit.Params[paramUpdateColumns].([]string); ok && len(updates) > 0 {
+			sets := make([]string, len(updates))
+			for i, column := range updates {
+				sets[i] = column + " = VALUES(" + column + ")"
+			}
+			sb.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "))
+		}
+	case consts.OpUpdate:
+		columns := sortedKeys(unit.Data)
+		sets := make([]string, len(columns))
+		for i, column := range columns {
+			if m, ok := unit.Data[column].(map[string]interface{}); ok {
+				if expr, ok := m[exprMarker].(*Expression); ok { // 更新表达式
+					sets[i] = column + " = " + expr.SQL
+					args = append(args, expr.Args...)
+					continue
+				}
+			}
+
+			sets[i] = column + " = ?"
+			args = append(args, unit.Data[column])
+		}
+
+		sb.WriteString("UPDATE " + table + " SET " + strings.Join(sets, ", "))
+		args = writeWhere(&sb, " WHERE ", unit.Where, args)
+	case consts.OpDelete:
+		sb.WriteString("DELETE FROM " + table)
+		args = writeWhere(&sb, " WHERE ", unit.Where, args)
+	case consts.OpTransaction:
+		sb.WriteString("BEGIN")
+		if level, ok := unit.Params[paramIsolation].(string); ok {
+			sb.WriteString(" ISOLATION LEVEL " + level)
+		}
+		if readOnly, _ := unit.Params[paramReadOnly].(bool); readOnly {
+			sb.WriteString(" READ ONLY")
+		}
+		sb.WriteString("; ... COMMIT")
+	default:
+		sb.WriteString(strings.ToUpper(unit.Op) + " " + table)
+	}
+
+	if returning, ok := unit.Params[paramReturning].([]string); ok && len(returning) > 0 {
+		sb.WriteString(" RETURNING " + strings.Join(returning, ", "))
+	}
+
+	return sb.String(), args
+}
+
+func redisStatement(unit *proto.Unit) string {
+	parts := []string{strings.ToUpper(unit.Op)}
+
+	if unit.Key != "" {
+		parts = append(parts, unit.Prefix+unit.Key)
+	}
+
+	if unit.Field != "" {
+		parts = append(parts, unit.Field)
+	}
+
+	if unit.Val != nil {
+		parts = append(parts, fmt.Sprint(unit.Val))
+	}
+
+	for _, arg := range unit.Args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+
+	for _, k := range sortedKeys(unit.Params) {
+		parts = append(parts, strings.ToUpper(k), fmt.Sprint(unit.Params[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// writeWhere 近似渲染 where 条件，key 按字母序排列
+func writeWhere(sb *strings.Builder, prefix string, where map[string]interface{}, args []interface{}) []interface{} {
+	if len(where) == 0 {
+		return args
+	}
+
+	cond, args := whereCond(where, consts.AND, args)
+	sb.WriteString(prefix + cond)
+	return args
+}
+
+func whereCond(where map[string]interface{}, conj string, args []interface{}) (string, []interface{}) {
+	conds := make([]string, 0, len(where))
+
+	for _, key := range sortedKeys(where) {
+		value := where[key]
+
+		// AND、OR、NOT 关联词，可带有注释，例如 OR #comment
+		word := trimComment(key)
+		if sub, ok := toMap(value); ok && (word == consts.AND || word == consts.OR || word == consts.NOT) {
+			var cond string
+			if word == consts.NOT {
+				cond, args = whereCond(sub, consts.AND, args)
+				conds = append(conds, "NOT ("+cond+")")
+			} else {
+				cond, args = whereCond(sub, word, args)
+				conds = append(conds, "("+cond+")")
+			}
+			continue
+		}
+
+		if word == existsWord {
+			var cond string
+			cond, args = existsCond(value, args)
+			conds = append(conds, cond)
+			continue
+		}
+
+		var cond string
+		cond, args = fieldCond(key, value, args)
+		conds = append(conds, cond)
+	}
+
+	return strings.Join(conds, " "+conj+" "), args
+}
+
+func existsCond(value interface{}, args []interface{}) (string, []interface{}) {
+	name, where, _ := existsSub(value)
+	if len(where) == 0 {
+		return "EXISTS (SELECT 1 FROM " + name + ")", args
+	}
+
+	cond, args := whereCond(where, consts.AND, args)
+	return "EXISTS (SELECT 1 FROM " + name + " WHERE " + cond + ")", args
+}
+
+func fieldCond(key string, value interface{}, args []interface{}) (string, []interface{}) {
+	field, op := splitOP(key)
+
+	if value == nil {
+		if op == consts.OPNot {
+			return field + " IS NOT NULL", args
+		}
+		return field + " IS NULL", args
+	}
+
+	rv := reflect.ValueOf(value)
+	array := isArray(value)
+
+	switch op {
+	case consts.OPBetween, consts.OPNotBetween:
+		not := ""
+		if op == consts.OPNotBetween {
+			not = "NOT "
+		}
+
+		if array && rv.Len() == 2 {
+			return field + " " + not + "BETWEEN ? AND ?", append(args, rv.Index(0).Interface(), rv.Index(1).Interface())
+		}
+		return field + " " + not + "BETWEEN ?", append(args, value)
+	case consts.OPLike:
+		return field + " LIKE ?", append(args, value)
+	case consts.OPNotLike:
+		return field + " NOT LIKE ?", append(args, value)
+	case consts.OPGt, consts.OPGte, consts.OPLt, consts.OPLte:
+		return field + " " + op + " ?", append(args, value)
+	case consts.OPMatch, consts.OPMatchPhrase:
+		return "MATCH(" + field + ") AGAINST (?)", append(args, value)
+	case consts.OPNotMatch, consts.OPNotMatchPhrase:
+		return "NOT MATCH(" + field + ") AGAINST (?)", append(args, value)
+	}
+
+	if array {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", rv.Len()), ", ")
+		for i := 0; i < rv.Len(); i++ {
+			args = append(args, rv.Index(i).Interface())
+		}
+
+		if op == consts.OPNot {
+			return field + " NOT IN (" + placeholders + ")", args
+		}
+		return field + " IN (" + placeholders + ")", args
+	}
+
+	if op == consts.OPNot {
+		return field + " != ?", append(args, value)
+	}
+
+	return field + " = ?", append(args, value)
+}
+
+// splitOP 拆分 where key 的字段与操作符，例如 age>= 拆分为 age 与 >=
+func splitOP(key string) (string, string) {
+	key = trimComment(key)
+
+	i := len(key)
+	for i > 0 && strings.ContainsRune("!()<>=~?*", rune(key[i-1])) {
+		i--
+	}
+
+	op := key[i:]
+	if op == consts.OPEqual {
+		op = ""
+	}
+
+	return strings.TrimSpace(key[:i]), op
+}
+
+// trimComment 去掉 key 中 # 开头的注释
+func trimComment(key string) string {
+	return strings.TrimSpace(strings.SplitN(key, "#", 2)[0])
+}
+
+// isArray 是否为数组（[]byte 除外）
+func isArray(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+func joinOn(on map[string]string) string {
+	keys := make([]string, 0, len(on))
+	for k := range on {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := make([]string, 0, len(on))
+	for _, k := range keys {
+		conds = append(conds, k+" = "+on[k])
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// orderBy order 中 - 开头表示降序
+func orderBy(orders []string) string {
+	ret := make([]string, len(orders))
+	for i, order := range orders {
+		if strings.HasPrefix(order, "-") {
+			ret[i] = strings.TrimPrefix(order, "-") + " DESC"
+		} else {
+			ret[i] = strings.TrimPrefix(order, "+")
+		}
+	}
+	return strings.Join(ret, ", ")
+}
+
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case Where:
+		return m, true
+	case AND:
+		return m, true
+	case OR:
+		return m, true
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
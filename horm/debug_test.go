@@ -0,0 +1,65 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugNotModifyQuery(t *testing.T) {
+	q := NewQuery("student").Where(Where{"id": 1})
+	q.SetTimeout(time.Second)
+
+	sub := NewQuery("course").FindAll(Where{"@student_id": "/student.id"})
+	q.AddSub(sub)
+	q.Next("teacher").Find(Where{"id": 2})
+
+	trans := NewTransaction("student", NewQuery("student").Update(Map{"age": 1}, Where{"id": 1}))
+
+	tests := []struct {
+		name   string
+		render func() string
+	}{
+		{"string", q.String},
+		{"debug", q.Debug},
+		{"format", func() string { return fmt.Sprintf("%v", q) }},
+		{"sub", sub.String},
+		{"transaction", trans.String},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := tt.render(); !strings.Contains(out, "-- ") {
+				t.Fatalf("render result %s", out)
+			}
+
+			if q.Unit.Size != -1 || q.Unit.Extend != nil || q.Unit.Sub != nil || sub.Unit.Size != 100 {
+				t.Fatalf("query modified by render, size %d extend %v sub %v", q.Unit.Size, q.Unit.Extend, q.Unit.Sub)
+			}
+
+			if trans.Unit.Trans != nil || trans.trans.Unit.Size != -1 {
+				t.Fatalf("transaction modified by render, trans %v", trans.Unit.Trans)
+			}
+		})
+	}
+
+	// 渲染后才设置操作，仍然取默认 100 条
+	if q.FindAll(); q.Unit.Size != 100 {
+		t.Fatalf("default limit lost after render, size %d", q.Unit.Size)
+	}
+}
@@ -15,10 +15,17 @@
 package horm
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/proto"
 )
 
+// ErrDryRun DryRun 模式下不发送请求，执行查询返回该错误
+var ErrDryRun = errors.New("horm: dry run, request is not sent")
+
 // Error 返回信息
 type Error proto.Error
 
@@ -86,3 +93,227 @@ func IsAllSuccess(results []*proto.ModRet) bool {
 
 	return true
 }
+
+// 错误类别哨兵，可通过 errors.Is(err, horm.ErrTimeout) 判断
+var (
+	ErrTimeout   = errors.New("horm: timeout")       // 客户端或服务端超时
+	ErrCanceled  = errors.New("horm: canceled")      // 调用方取消请求
+	ErrNetwork   = errors.New("horm: network error") // 连接失败、网络错误、帧读取失败
+	ErrRoute     = errors.New("horm: route error")   // 选址失败
+	ErrOverload  = errors.New("horm: overload")      // 服务端过载
+	ErrAuth      = errors.New("horm: auth failed")   // 鉴权失败、无权限
+	ErrNotFound  = errors.New("horm: not found")     // 未找到表、数据库配置
+	ErrDuplicate = errors.New("horm: duplicate key") // 主键、唯一键冲突
+	ErrDatabase  = errors.New("horm: database error")
+	ErrPlugin    = errors.New("horm: plugin error")
+)
+
+// 数据库返回的主键、唯一键冲突错误信息
+var duplicateMsgs = []string{
+	"Duplicate entry",                   // mysql
+	"duplicate key value",               // postgresql
+	"version_conflict_engine_exception", // elastic
+}
+
+// QueryError 查询错误，在 errs.Error 的基础上带有执行单元名、request_id、节点地址，
+// 可通过 errors.Is 判断错误类别，通过 errors.As 获取 *TimeoutError 等具体错误类型或原始的 *errs.Error。
+// 注意 Exec、PExec、CompExec 返回的错误不再是 *errs.Error，err.(*errs.Error) 类型断言需要改为 errors.As。
+type QueryError struct {
+	Type      errs.EType // 错误类型
+	Code      int        // 错误码
+	Msg       string     // 错误信息
+	Sql       string     // 异常语句
+	Unit      string     // 执行单元名，并行、复合查询整体失败时为空
+	RequestID uint64     // request_id
+	Address   string     // 节点地址
+
+	kind error       // 错误类别哨兵
+	err  *errs.Error // 原始错误
+}
+
+// Error implements error.
+func (e *QueryError) Error() string {
+	var sb strings.Builder
+	if !strings.HasPrefix(e.Msg, "[request_id=") { // 解码等错误信息已包含 request_id
+		sb.WriteString(fmt.Sprintf("[request_id=%d] ", e.RequestID))
+	}
+	if e.Unit != "" {
+		sb.WriteString("[unit=" + e.Unit + "] ")
+	}
+	if e.Address != "" {
+		sb.WriteString("[node=" + e.Address + "] ")
+	}
+	sb.WriteString(e.err.Error())
+	return sb.String()
+}
+
+// Unwrap 返回原始的 *errs.Error
+func (e *QueryError) Unwrap() error {
+	return e.err
+}
+
+// Is 判断是否属于某个错误类别
+func (e *QueryError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// Kind 错误类别哨兵，未分类时为 nil
+func (e *QueryError) Kind() error {
+	return e.kind
+}
+
+// Temporary 是否是临时错误，例如超时、网络错误、服务端过载
+func (e *QueryError) Temporary() bool {
+	return e.kind == ErrTimeout || e.kind == ErrNetwork || e.kind == ErrOverload
+}
+
+// Retryable 是否可以重试，临时错误与选址失败可以重试
+func (e *QueryError) Retryable() bool {
+	return e.Temporary() || e.kind == ErrRoute
+}
+
+// 具体错误类型，可通过 errors.As 获取
+type (
+	TimeoutError   struct{ *QueryError } // 超时
+	CanceledError  struct{ *QueryError } // 调用方取消
+	NetworkError   struct{ *QueryError } // 网络错误
+	RouteError     struct{ *QueryError } // 选址失败
+	OverloadError  struct{ *QueryError } // 服务端过载
+	AuthError      struct{ *QueryError } // 鉴权失败
+	NotFoundError  struct{ *QueryError } // 未找到表、数据库配置
+	DuplicateError struct{ *QueryError } // 主键、唯一键冲突
+	DatabaseError  struct{ *QueryError } // 数据库错误
+	PluginError    struct{ *QueryError } // 插件错误
+)
+
+func (e *TimeoutError) Unwrap() error   { return e.QueryError }
+func (e *CanceledError) Unwrap() error  { return e.QueryError }
+func (e *NetworkError) Unwrap() error   { return e.QueryError }
+func (e *RouteError) Unwrap() error     { return e.QueryError }
+func (e *OverloadError) Unwrap() error  { return e.QueryError }
+func (e *AuthError) Unwrap() error      { return e.QueryError }
+func (e *NotFoundError) Unwrap() error  { return e.QueryError }
+func (e *DuplicateError) Unwrap() error { return e.QueryError }
+func (e *DatabaseError) Unwrap() error  { return e.QueryError }
+func (e *PluginError) Unwrap() error    { return e.QueryError }
+
+// IsRetryable 错误是否可以重试
+func IsRetryable(err error) bool {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		return qe.Retryable()
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		return (&QueryError{kind: classify(e)}).Retryable()
+	}
+
+	return false
+}
+
+// IsTemporary 错误是否是临时错误
+func IsTemporary(err error) bool {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		return qe.Temporary()
+	}
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		return (&QueryError{kind: classify(e)}).Temporary()
+	}
+
+	return false
+}
+
+// classify 错误分类
+func classify(e *errs.Error) error {
+	switch e.Type {
+	case errs.ETypePlugin:
+		return ErrPlugin
+	case errs.ETypeDatabase:
+		for _, msg := range duplicateMsgs {
+			if strings.Contains(e.Msg, msg) {
+				return ErrDuplicate
+			}
+		}
+		return ErrDatabase
+	}
+
+	switch {
+	case e.Code == errs.ErrClientTimeout || e.Code == errs.ErrServerTimeout:
+		return ErrTimeout
+	case e.Code == errs.ErrClientCanceled:
+		return ErrCanceled
+	case e.Code == errs.ErrClientConnect || e.Code == errs.ErrClientNet || e.Code == errs.ErrClientReadFrame:
+		return ErrNetwork
+	case e.Code == errs.ErrClientRoute:
+		return ErrRoute
+	case e.Code == errs.ErrServerOverload:
+		return ErrOverload
+	case e.Code == errs.ErrServerAuthFail || e.Code == errs.ErrHasNoTableRight || e.Code == errs.ErrHasNoDBRight ||
+		e.Code == errs.ErrNotFindAppid || e.Code == errs.ErrTableVerifyFailed:
+		return ErrAuth
+	case e.Code == errs.ErrNotFindName || e.Code == errs.ErrDBConfigNotFound:
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// wrapError 将 *errs.Error 包装为具体错误类型，其他错误原样返回
+// param: unit 执行单元名
+// param: head 首查询，用于获取 request_id 与节点地址
+func wrapError(err error, unit string, head *Query) error {
+	if err == nil {
+		return nil
+	}
+
+	var qe *QueryError
+	if errors.As(err, &qe) { // 已包装
+		return err
+	}
+
+	e, ok := err.(*errs.Error)
+	if !ok {
+		return err
+	}
+
+	qe = &QueryError{
+		Type:      e.Type,
+		Code:      e.Code,
+		Msg:       e.Msg,
+		Sql:       e.Sql,
+		Unit:      unit,
+		RequestID: head.RequestID,
+		Address:   head.addr,
+		kind:      classify(e),
+		err:       e,
+	}
+
+	switch qe.kind {
+	case ErrTimeout:
+		return &TimeoutError{qe}
+	case ErrCanceled:
+		return &CanceledError{qe}
+	case ErrNetwork:
+		return &NetworkError{qe}
+	case ErrRoute:
+		return &RouteError{qe}
+	case ErrOverload:
+		return &OverloadError{qe}
+	case ErrAuth:
+		return &AuthError{qe}
+	case ErrNotFound:
+		return &NotFoundError{qe}
+	case ErrDuplicate:
+		return &DuplicateError{qe}
+	case ErrDatabase:
+		return &DatabaseError{qe}
+	case ErrPlugin:
+		return &PluginError{qe}
+	default:
+		return qe
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+)
+
+func TestWrapError(t *testing.T) {
+	head := &Query{RequestID: 10086, addr: "127.0.0.1:8180"}
+
+	tests := []struct {
+		name      string
+		err       *errs.Error
+		kind      error
+		retryable bool
+	}{
+		{"client timeout", &errs.Error{Code: errs.ErrClientTimeout}, ErrTimeout, true},
+		{"server timeout", &errs.Error{Code: errs.ErrServerTimeout}, ErrTimeout, true},
+		{"canceled", &errs.Error{Code: errs.ErrClientCanceled}, ErrCanceled, false},
+		{"network", &errs.Error{Code: errs.ErrClientNet}, ErrNetwork, true},
+		{"route", &errs.Error{Code: errs.ErrClientRoute}, ErrRoute, true},
+		{"overload", &errs.Error{Code: errs.ErrServerOverload}, ErrOverload, true},
+		{"auth", &errs.Error{Code: errs.ErrHasNoTableRight}, ErrAuth, false},
+		{"not found", &errs.Error{Code: errs.ErrNotFindName}, ErrNotFound, false},
+		{"duplicate", &errs.Error{Type: errs.ETypeDatabase, Msg: "Duplicate entry '1' for key 'PRIMARY'"}, ErrDuplicate, false},
+		{"database", &errs.Error{Type: errs.ETypeDatabase, Msg: "syntax error"}, ErrDatabase, false},
+		{"plugin", &errs.Error{Type: errs.ETypePlugin}, ErrPlugin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.err, "student", head)
+
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("errors.Is(%v) = false", tt.kind)
+			}
+
+			if IsRetryable(err) != tt.retryable {
+				t.Fatalf("IsRetryable = %v, want %v", IsRetryable(err), tt.retryable)
+			}
+
+			var e *errs.Error
+			if !errors.As(err, &e) || e != tt.err {
+				t.Fatalf("errors.As did not return the original *errs.Error")
+			}
+
+			var qe *QueryError
+			if !errors.As(err, &qe) || qe.Unit != "student" || qe.RequestID != 10086 || qe.Address != "127.0.0.1:8180" {
+				t.Fatalf("errors.As QueryError got %+v", qe)
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"fmt"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+// Column 列，通过 Col 创建，用于构建类型化的查询条件表达式
+type Column string
+
+// Cond 查询条件表达式，可以通过 And、Or、Not 组合，最终编译为 Where 条件，例如：
+// horm.Col("age").Gt(10).And(horm.Col("name").Like("a%"), horm.Or(horm.Col("gender").Eq(1), horm.Col("score").IsNull()))
+// 相同列相同操作符的条件会自动加上 #n 注释以避免 map key 冲突。
+type Cond struct {
+	conj  string      // 组合条件 AND、OR、NOT，为空时为单个条件
+	key   string      // 单个条件的 key，列名 + 操作符
+	value interface{} // 单个条件的值
+	conds []*Cond     // 组合条件的子条件
+	err   error       // 构建错误
+}
+
+// 合法的操作符
+var validOPs = map[string]bool{
+	"":                      true,
+	consts.OPEqual:          true,
+	consts.OPBetween:        true,
+	consts.OPNotBetween:     true,
+	consts.OPGt:             true,
+	consts.OPGte:            true,
+	consts.OPLt:             true,
+	consts.OPLte:            true,
+	consts.OPNot:            true,
+	consts.OPLike:           true,
+	consts.OPNotLike:        true,
+	consts.OPMatchPhrase:    true,
+	consts.OPNotMatchPhrase: true,
+	consts.OPMatch:          true,
+	consts.OPNotMatch:       true,
+}
+
+// existsWord EXISTS 子查询条件的 key，值为子查询执行单元，可带有注释，例如 EXISTS #2，需统一接入服务支持
+const existsWord = "EXISTS"
+
+// Col 创建列
+func Col(name string) Column {
+	return Column(name)
+}
+
+// Op 使用指定操作符构建条件，操作符必须是 consts 中定义的 OP 操作符
+func (c Column) Op(op string, value interface{}) *Cond {
+	if !validOPs[op] {
+		return &Cond{err: errs.Newf(errs.ErrReqParamInvalid, "column %s has invalid where operator %q", c, op)}
+	}
+
+	if c == "" {
+		return &Cond{err: errs.New(errs.ErrReqParamInvalid, "where column is empty")}
+	}
+
+	if op == "" || op == consts.OPEqual {
+		return &Cond{key: string(c), value: value}
+	}
+
+	return &Cond{key: string(c) + " " + op, value: value}
+}
+
+// Eq 等于，值为数组时为 IN
+func (c Column) Eq(value interface{}) *Cond {
+	return c.Op(consts.OPEqual, value)
+}
+
+// Ne 不等于，值为数组时为 NOT IN
+func (c Column) Ne(value interface{}) *Cond {
+	return c.Op(consts.OPNot, value)
+}
+
+// Gt 大于
+func (c Column) Gt(value interface{}) *Cond {
+	return c.Op(consts.OPGt, value)
+}
+
+// Gte 大于等于
+func (c Column) Gte(value interface{}) *Cond {
+	return c.Op(consts.OPGte, value)
+}
+
+// Lt 小于
+func (c Column) Lt(value interface{}) *Cond {
+	return c.Op(consts.OPLt, value)
+}
+
+// Lte 小于等于
+func (c Column) Lte(value interface{}) *Cond {
+	return c.Op(consts.OPLte, value)
+}
+
+// Between 在区间 [start, end] 内
+func (c Column) Between(start, end interface{}) *Cond {
+	return c.Op(consts.OPBetween, []interface{}{start, end})
+}
+
+// NotBetween 不在区间 [start, end] 内
+func (c Column) NotBetween(start, end interface{}) *Cond {
+	return c.Op(consts.OPNotBetween, []interface{}{start, end})
+}
+
+// In 在集合内
+func (c Column) In(values ...interface{}) *Cond {
+	return c.Op(consts.OPEqual, inValues(values))
+}
+
+// NotIn 不在集合内
+func (c Column) NotIn(values ...interface{}) *Cond {
+	return c.Op(consts.OPNot, inValues(values))
+}
+
+// IsNull 为 NULL
+func (c Column) IsNull() *Cond {
+	return c.Op(consts.OPEqual, nil)
+}
+
+// IsNotNull 不为 NULL
+func (c Column) IsNotNull() *Cond {
+	return c.Op(consts.OPNot, nil)
+}
+
+// Like 模糊匹配（或 es 的部分匹配），值为数组时任一匹配
+func (c Column) Like(value interface{}) *Cond {
+	return c.Op(consts.OPLike, value)
+}
+
+// NotLike 模糊匹配排除，值为数组时全部不匹配
+func (c Column) NotLike(value interface{}) *Cond {
+	return c.Op(consts.OPNotLike, value)
+}
+
+// Match es 全文搜索 match
+func (c Column) Match(value interface{}) *Cond {
+	return c.Op(consts.OPMatch, value)
+}
+
+// NotMatch es 全文搜索排除
+func (c Column) NotMatch(value interface{}) *Cond {
+	return c.Op(consts.OPNotMatch, value)
+}
+
+// MatchPhrase es 短语匹配 match_phrase
+func (c Column) MatchPhrase(value interface{}) *Cond {
+	return c.Op(consts.OPMatchPhrase, value)
+}
+
+// NotMatchPhrase es 短语匹配排除
+func (c Column) NotMatchPhrase(value interface{}) *Cond {
+	return c.Op(consts.OPNotMatchPhrase, value)
+}
+
+// Ref 引用复合查询中其他执行单元的结果，path 为引用路径，例如 /student.identify、../.course，
+// 引用在服务端先执行被引用单元再代入结果，可以替代 IN 子查询。在 Exists 子查询中 ../.列名 引用外层语句的列。
+func (c Column) Ref(path string) *Cond {
+	if c == "" {
+		return &Cond{err: errs.New(errs.ErrReqParamInvalid, "where column is empty")}
+	}
+	return &Cond{key: "@" + string(c), value: path}
+}
+
+// Exists 子查询存在记录，编译为 {"EXISTS": 子查询执行单元}，子查询只能是单个 find、find_all 语句，
+// 子查询条件通过 Ref("../.列名") 与外层语句关联，例如：
+// horm.Exists(horm.NewQuery("course").WhereExpr(horm.Col("student_id").Ref("../.id")))
+// 即 EXISTS (SELECT 1 FROM course WHERE student_id = student.id)
+func Exists(sub *Query) *Cond {
+	unit, err := existsUnit(sub)
+	if err != nil {
+		return &Cond{err: err}
+	}
+	return &Cond{key: existsWord, value: unit}
+}
+
+// NotExists 子查询不存在记录，即 NOT (EXISTS subquery)
+func NotExists(sub *Query) *Cond {
+	return Not(Exists(sub))
+}
+
+// existsUnit 复制子查询执行单元，子查询之后的修改不影响已构建的条件
+func existsUnit(sub *Query) (*proto.Unit, error) {
+	if sub == nil || sub.Unit == nil {
+		return nil, errs.New(errs.ErrReqParamInvalid, "exists subquery is nil")
+	}
+
+	if sub.Error != nil {
+		return nil, sub.Error
+	}
+
+	if sub.GetHead() != sub || sub.next != nil || sub.sub != nil || sub.trans != nil {
+		return nil, errs.Newf(errs.ErrReqParamInvalid,
+			"exists subquery %s must be a single statement", sub.Unit.Name)
+	}
+
+	if sub.Unit.Params[paramLock] != nil {
+		return nil, errs.Newf(errs.ErrReqParamInvalid, "exists subquery %s can not lock rows", sub.Unit.Name)
+	}
+
+	unit := sub.clone().Unit
+
+	switch unit.Op {
+	case "":
+		unit.Op = consts.OpFind
+	case consts.OpFind, consts.OpFindAll:
+	default:
+		return nil, errs.Newf(errs.ErrReqParamInvalid,
+			"exists subquery %s op must be find or find_all, got %s", unit.Name, unit.Op)
+	}
+
+	if unit.Size < 0 {
+		unit.Size = 0
+	}
+
+	return unit, nil
+}
+
+// And 与其他条件 AND 组合
+func (c *Cond) And(conds ...*Cond) *Cond {
+	return And(append([]*Cond{c}, conds...)...)
+}
+
+// Or 与其他条件 OR 组合
+func (c *Cond) Or(conds ...*Cond) *Cond {
+	return Or(append([]*Cond{c}, conds...)...)
+}
+
+// And 所有条件同时满足
+func And(conds ...*Cond) *Cond {
+	return &Cond{conj: consts.AND, conds: conds}
+}
+
+// Or 任一条件满足
+func Or(conds ...*Cond) *Cond {
+	return &Cond{conj: consts.OR, conds: conds}
+}
+
+// Not 所有条件同时满足时取反，即 NOT (cond1 AND cond2 ...)
+func Not(conds ...*Cond) *Cond {
+	return &Cond{conj: consts.NOT, conds: conds}
+}
+
+// Where 编译为 Where 条件
+func (c *Cond) Where() (Where, error) {
+	w := Where{}
+	if err := c.compile(w, consts.AND); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// String 编译后的 Where 条件
+func (c *Cond) String() string {
+	w, err := c.Where()
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprint(map[string]interface{}(w))
+}
+
+// compile 将条件编译到 w 中，parent 为 w 的组合关系
+func (c *Cond) compile(w Where, parent string) error {
+	if c == nil {
+		return errs.New(errs.ErrReqParamInvalid, "where condition is nil")
+	}
+
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.conj == "" {
+		putUnique(w, c.key, c.value)
+		return nil
+	}
+
+	if len(c.conds) == 0 {
+		return errs.Newf(errs.ErrReqParamInvalid, "%s condition is empty", c.conj)
+	}
+
+	if c.conj == consts.AND && parent == consts.AND { // AND 嵌套 AND 直接展开
+		for _, sub := range c.conds {
+			if err := sub.compile(w, consts.AND); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.conj == consts.OR && len(c.conds) == 1 { // 单个条件的 OR 等同于该条件
+		return c.conds[0].compile(w, parent)
+	}
+
+	subWhere := Where{}
+	conj := c.conj
+	if conj == consts.NOT {
+		conj = consts.AND // NOT 下的条件为 AND 关系
+	}
+
+	for _, sub := range c.conds {
+		if err := sub.compile(subWhere, conj); err != nil {
+			return err
+		}
+	}
+
+	putUnique(w, c.conj, subWhere)
+	return nil
+}
+
+// WhereExpr 使用条件表达式设置查询条件，会与已有条件 AND 组合
+func (s *Query) WhereExpr(cond *Cond) *Query {
+	w, err := cond.Where()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	if s.Unit.Where == nil {
+		s.Unit.Where = w
+		return s
+	}
+
+	for k, v := range w {
+		putUnique(s.Unit.Where, k, v)
+	}
+
+	return s
+}
+
+// Validate 校验条件中的操作符是否合法
+func (w Where) Validate() error {
+	return validateWhere(w)
+}
+
+func validateWhere(w map[string]interface{}) error {
+	for key, value := range w {
+		word := trimComment(key)
+		if word == existsWord {
+			if err := validateExists(key, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if word == consts.AND || word == consts.OR || word == consts.NOT {
+			switch v := value.(type) {
+			case Where:
+				if err := validateWhere(v); err != nil {
+					return err
+				}
+			case map[string]interface{}:
+				if err := validateWhere(v); err != nil {
+					return err
+				}
+			case []Where:
+				for _, sub := range v {
+					if err := validateWhere(sub); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		field, op := splitOP(key)
+		if field == "" {
+			return errs.Newf(errs.ErrReqParamInvalid, "where key %q has no column", key)
+		}
+
+		if !validOPs[op] {
+			return errs.Newf(errs.ErrReqParamInvalid, "where key %q has invalid operator %q", key, op)
+		}
+	}
+
+	return nil
+}
+
+// validateExists 校验 EXISTS 子查询的表名与条件
+func validateExists(key string, value interface{}) error {
+	name, where, ok := existsSub(value)
+	if !ok || name == "" {
+		return errs.Newf(errs.ErrReqParamInvalid, "where key %q must be an exists subquery unit", key)
+	}
+	return validateWhere(where)
+}
+
+// existsSub EXISTS 子查询的表名与条件，值为执行单元或其 json 解码后的 map
+func existsSub(value interface{}) (string, map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case *proto.Unit:
+		if v == nil {
+			return "", nil, false
+		}
+		return v.Name, v.Where, true
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		where, _ := toMap(v["where"])
+		return name, where, true
+	}
+	return "", nil, false
+}
+
+// putUnique 设置条件，key 已存在时加上 #n 注释区分
+func putUnique(w map[string]interface{}, key string, value interface{}) {
+	if _, ok := w[key]; !ok {
+		w[key] = value
+		return
+	}
+
+	for i := 2; ; i++ {
+		k := fmt.Sprintf("%s #%d", key, i)
+		if _, ok := w[k]; !ok {
+			w[k] = value
+			return
+		}
+	}
+}
+
+// inValues 集合参数，仅传入一个数组时直接使用该数组
+func inValues(values []interface{}) interface{} {
+	if len(values) == 1 {
+		if isArray(values[0]) {
+			return values[0]
+		}
+	}
+	return values
+}
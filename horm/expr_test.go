@@ -0,0 +1,161 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+)
+
+func TestCondCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		cond *Cond
+		want Where
+	}{
+		{"eq", Col("age").Eq(10), Where{"age": 10}},
+		{"gt", Col("age").Gt(10), Where{"age >": 10}},
+		{"in", Col("id").In(1, 2, 3), Where{"id": []interface{}{1, 2, 3}}},
+		{"in slice", Col("id").In([]int{1, 2}), Where{"id": []int{1, 2}}},
+		{"not in", Col("id").NotIn(1, 2), Where{"id !": []interface{}{1, 2}}},
+		{"is null", Col("name").IsNull(), Where{"name": nil}},
+		{"between", Col("age").Between(1, 9), Where{"age ()": []interface{}{1, 9}}},
+		{"ref", Col("id").Ref("/student.id"), Where{"@id": "/student.id"}},
+		{"and flatten", Col("age").Gt(1).And(Col("name").Like("a%")), Where{"age >": 1, "name ~": "a%"}},
+		{"duplicate key", And(Col("age").Gt(1), Col("age").Gt(2)), Where{"age >": 1, "age > #2": 2}},
+		{"or", Or(Col("a").Eq(1), Col("b").Eq(2)), Where{"OR": Where{"a": 1, "b": 2}}},
+		{"single or", Or(Col("a").Eq(1)), Where{"a": 1}},
+		{"not", Not(Col("a").Eq(1), Col("b").Eq(2)), Where{"NOT": Where{"a": 1, "b": 2}}},
+		{"nested", Col("a").Eq(1).And(Or(Col("b").Eq(2), Col("c").Eq(3)), Or(Col("d").Eq(4), Col("e").Eq(5))),
+			Where{"a": 1, "OR": Where{"b": 2, "c": 3}, "OR #2": Where{"d": 4, "e": 5}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cond.Where()
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondCompileError(t *testing.T) {
+	tests := []struct {
+		name string
+		cond *Cond
+	}{
+		{"invalid op", Col("age").Op("<>", 1)},
+		{"empty column", Col("").Eq(1)},
+		{"empty ref column", Col("").Ref("/student.id")},
+		{"empty and", Col("a").Eq(1).And(Or())},
+		{"nil exists", Exists(nil)},
+		{"exists write", Exists(NewQuery("course").Delete(Where{"id": 1}))},
+		{"exists compound", Exists(NewQuery("course").Find().AddSub(NewQuery("teacher").Find()))},
+		{"exists next", Exists(NewQuery("course").Next("teacher"))},
+		{"exists lock", Exists(NewQuery("course").Find().ForUpdate())},
+		{"exists error", NotExists(NewQuery("course").WhereExpr(Col("a").Op("bad", 1)))},
+		{"nil cond", And(Col("a").Eq(1), nil)},
+		{"nested error", Or(Col("a").Eq(1), Col("b").Op("bad", 2))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cond.Where(); err == nil {
+				t.Fatal("expect compile error")
+			}
+		})
+	}
+}
+
+func TestWhereValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		where Where
+		valid bool
+	}{
+		{"plain", Where{"age >": 1, "name ~": "a%"}, true},
+		{"comment", Where{"age > #2": 1}, true},
+		{"nested", Where{"OR": Where{"a": 1, "b <=": 2}}, true},
+		{"invalid op", Where{"age <>": 1}, false},
+		{"nested invalid", Where{"NOT": map[string]interface{}{"a <>": 1}}, false},
+		{"exists", Where{"EXISTS": &proto.Unit{Name: "course", Where: Where{"@student_id": "../.id"}}}, true},
+		{"exists map", Where{"EXISTS #2": map[string]interface{}{"name": "course", "where": map[string]interface{}{"a": 1}}}, true},
+		{"exists invalid", Where{"EXISTS": &proto.Unit{Name: "course", Where: Where{"a <>": 1}}}, false},
+		{"exists no unit", Where{"EXISTS": 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.where.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("validate error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCondExists(t *testing.T) {
+	course := NewQuery("course").WhereExpr(Col("student_id").Ref("../.id").And(Col("name").Eq("math")))
+
+	w, err := Col("age").Gt(18).And(Exists(course), NotExists(NewQuery("absence").FindAll())).Where()
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+
+	unit, ok := w["EXISTS"].(*proto.Unit)
+	if !ok || unit.Name != "course" || unit.Op != consts.OpFind || unit.Size != 0 ||
+		!reflect.DeepEqual(map[string]interface{}(unit.Where), map[string]interface{}{"@student_id": "../.id", "name": "math"}) {
+		t.Fatalf("exists unit %+v", w["EXISTS"])
+	}
+
+	not, ok := w["NOT"].(Where)
+	if unit, _ := not["EXISTS"].(*proto.Unit); !ok || unit == nil || unit.Name != "absence" || unit.Op != consts.OpFindAll {
+		t.Fatalf("not exists %v", w["NOT"])
+	}
+
+	// 子查询之后的修改不影响已构建的条件
+	course.Eq("name", "art")
+	if unit.Where["name"] != "math" {
+		t.Fatalf("exists unit modified by subquery, where %v", unit.Where)
+	}
+
+	q := NewQuery("student").FindAll(w)
+	if err := w.Validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+
+	sql := q.String()
+	for _, want := range []string{"age > ?", "EXISTS (SELECT 1 FROM course WHERE @student_id = ? AND name = ?)",
+		"NOT (EXISTS (SELECT 1 FROM absence))"} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("sql %s, want contains %s", sql, want)
+		}
+	}
+
+	// 请求体中子查询为执行单元 json
+	body, err := json.Api.Marshal(q.Unit.Where)
+	if err != nil || !strings.Contains(string(body), `"EXISTS":{"name":"course","op":"find"`) {
+		t.Fatalf("request where %s error %v", body, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"time"
+
+	"github.com/horm-database/common/errs"
+)
+
+// exprMarker 更新数据中表达式值的标记，统一接入服务识别 {"@expr": {...}} 格式的值并生成对应数据库的表达式
+const exprMarker = "@expr"
+
+// Expression 更新表达式，用于原子更新列，例如 score = score + 5、updated_at = NOW()，仅 Update、UpdateKV 支持，
+// Insert、Replace 以及 SQL 数据库的 Upsert、InsertIgnore 的数据包含表达式时返回错误。
+// mysql、postgresql、clickhouse 使用 SQL 表达式，elastic 使用 painless 脚本：ctx._source.<列> = <Script>
+type Expression struct {
+	SQL    string                 `json:"sql,omitempty"`    // SQL 表达式，参数以 ? 占位
+	Args   []interface{}          `json:"args,omitempty"`   // SQL 参数
+	Script string                 `json:"script,omitempty"` // elastic painless 脚本表达式，参数以 params.xxx 引用
+	Params map[string]interface{} `json:"params,omitempty"` // painless 脚本参数
+}
+
+// Incr 列自增，n 为负数时自减，例如 horm.Map{"hours": horm.Incr("hours", 1)}
+func Incr(column string, n interface{}) *Expression {
+	param := column + "_incr"
+	return &Expression{
+		SQL:    column + " + ?",
+		Args:   []interface{}{n},
+		Script: "ctx._source." + column + " + params." + param,
+		Params: map[string]interface{}{param: n},
+	}
+}
+
+// Expr SQL 表达式，例如 horm.Map{"score": horm.Expr("GREATEST(score, ?)", 90)}，
+// elastic 需要通过 WithScript 指定对应的 painless 脚本
+func Expr(sql string, args ...interface{}) *Expression {
+	return &Expression{SQL: sql, Args: args}
+}
+
+// Script elastic painless 脚本表达式，例如 horm.Script("Math.max(ctx._source.score, params.score)", horm.Map{"score": 90})
+func Script(script string, params map[string]interface{}) *Expression {
+	return &Expression{Script: script, Params: params}
+}
+
+// Now 当前时间，SQL 为数据库服务器的 NOW()，elastic 没有服务端当前时间，为构造表达式时的客户端时间（RFC3339Nano），
+// 会受客户端时钟偏差影响，且同一个表达式多次执行时值不变
+func Now() *Expression {
+	return &Expression{
+		SQL:    "NOW()",
+		Script: "params.now",
+		Params: map[string]interface{}{"now": time.Now().Format(time.RFC3339Nano)},
+	}
+}
+
+// WithScript 指定 elastic painless 脚本
+func (e *Expression) WithScript(script string, params map[string]interface{}) *Expression {
+	e.Script = script
+	e.Params = params
+	return e
+}
+
+// encodeExprs 将更新数据中的表达式值转换为带标记的格式
+func (s *Query) encodeExprs() *Query {
+	for key, value := range s.Unit.Data {
+		expr, ok := value.(*Expression)
+		if !ok {
+			continue
+		}
+
+		if expr == nil || (expr.SQL == "" && expr.Script == "") {
+			continue
+		}
+
+		s.Unit.Data[key] = map[string]interface{}{exprMarker: expr}
+		delete(s.Unit.DataType, key)
+	}
+
+	return s
+}
+
+// checkNoExprs 插入、替换数据不支持更新表达式，表达式值会被当作普通数据写入，Upsert 冲突时也只会按插入值更新
+func (s *Query) checkNoExprs(data map[string]interface{}) {
+	for key, value := range data {
+		if _, ok := value.(*Expression); ok {
+			s.Error = errs.Newf(errs.ErrReqParamInvalid,
+				"%s data column %s can not be update expression, only update supports expression", s.Unit.Op, key)
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"testing"
+
+	"github.com/horm-database/common/errs"
+)
+
+func TestExpressionData(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  func() *Query
+		reject bool
+	}{
+		{"update", func() *Query { return NewQuery("student").Update(Map{"score": Incr("score", 5)}) }, false},
+		{"update kv", func() *Query { return NewQuery("student").UpdateKV("updated_at", Now()) }, false},
+		{"elastic upsert", func() *Query { return NewQuery("es_test").Upsert(Map{"score": Incr("score", 1)}, nil) }, false},
+		{"insert", func() *Query { return NewQuery("student").Insert(Map{"score": Incr("score", 5)}) }, true},
+		{"batch insert", func() *Query {
+			return NewQuery("student").Insert([]map[string]interface{}{{"id": 1}, {"id": 2, "updated_at": Now()}})
+		}, true},
+		{"replace", func() *Query { return NewQuery("student").Replace(Map{"score": Expr("score * ?", 2)}) }, true},
+		{"mysql upsert", func() *Query {
+			return NewQuery("mysql_test").Upsert(Map{"id": 1, "score": Incr("score", 1)}, []string{"id"})
+		}, true},
+		{"postgresql insert ignore", func() *Query {
+			return NewQuery("postgres_test").InsertIgnore(Map{"id": 1, "updated_at": Now()})
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query()
+			if tt.reject {
+				if errs.Code(q.Error) != errs.ErrReqParamInvalid {
+					t.Fatalf("error %v, want expression rejected", q.Error)
+				}
+				return
+			}
+
+			if q.Error != nil {
+				t.Fatalf("error: %v", q.Error)
+			}
+
+			marked := 0
+			for key, value := range q.Unit.Data {
+				if _, ok := value.(*Expression); ok {
+					t.Fatalf("column %s expression is not encoded", key)
+				}
+				if m, ok := value.(map[string]interface{}); ok && m[exprMarker] != nil {
+					marked++
+				}
+			}
+
+			if marked != 1 {
+				t.Fatalf("encoded expressions %d, want 1", marked)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/types"
+)
+
+// whereTag 条件结构体标签
+const whereTag = "where"
+
+// where 标签中的操作符名称
+var whereOPs = map[string]string{
+	"":           consts.OPEqual,
+	"eq":         consts.OPEqual,
+	"in":         consts.OPEqual,
+	"ne":         consts.OPNot,
+	"notin":      consts.OPNot,
+	"gt":         consts.OPGt,
+	"gte":        consts.OPGte,
+	"lt":         consts.OPLt,
+	"lte":        consts.OPLte,
+	"between":    consts.OPBetween,
+	"notbetween": consts.OPNotBetween,
+	"like":       consts.OPLike,
+	"notlike":    consts.OPNotLike,
+	"match":      consts.OPMatch,
+	"notmatch":   consts.OPNotMatch,
+	"phrase":     consts.OPMatchPhrase,
+	"notphrase":  consts.OPNotMatchPhrase,
+}
+
+// whereField 条件结构体字段
+type whereField struct {
+	index     []int
+	column    string
+	op        string
+	group     string // 嵌套结构体的组合条件 AND、OR、NOT
+	omitEmpty bool
+}
+
+// whereFieldsKey 条件结构体字段缓存 key
+type whereFieldsKey struct {
+	tag string
+	typ reflect.Type
+}
+
+var whereFields sync.Map // whereFieldsKey -> []*whereField
+
+// WhereStruct 根据结构体生成查询条件，会与已有条件 AND 组合，字段标签格式为 `where:"column,op,omitempty"`，例如：
+// `where:"age,gte"`、`where:"name,like,omitempty"`，column 为空时取 orm 标签列名，op 为空时为等于，
+// 可选 op 有 eq、ne、gt、gte、lt、lte、in、notin、between、notbetween、like、notlike、match、notmatch、phrase、notphrase。
+// 指针字段为 nil 时忽略，omitempty 忽略零值与空切片、空 map，切片字段为 IN / NOT IN，嵌套结构体字段通过 `where:",or"`、`where:",and"`、`where:",not"`
+// 组合为 OR、AND、NOT 条件组，匿名嵌入结构体展开，未打 where 标签的字段忽略。
+func (s *Query) WhereStruct(filter interface{}) *Query {
+	cond, err := structCond(s, reflect.ValueOf(filter), consts.AND)
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	if cond == nil {
+		return s
+	}
+
+	return s.WhereExpr(cond)
+}
+
+// structCond 结构体生成条件，没有条件时返回 nil
+func structCond(s *Query, rv reflect.Value, conj string) (*Cond, error) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, errs.Newf(errs.ErrReqParamInvalid, "where struct filter must be struct, got %s", rv.Kind())
+	}
+
+	fields, err := getWhereFields(s, rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []*Cond
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+
+		if f.group != "" {
+			sub, err := structCond(s, fv, f.group)
+			if err != nil {
+				return nil, err
+			}
+			if sub != nil {
+				conds = append(conds, sub)
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() { // 指针字段为 nil 表示不设置该条件
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		conds = append(conds, Col(f.column).Op(f.op, fv.Interface()))
+	}
+
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	return &Cond{conj: conj, conds: conds}, nil
+}
+
+// getWhereFields 解析结构体 where 标签，结果按类型缓存
+func getWhereFields(s *Query, t reflect.Type) ([]*whereField, error) {
+	key := whereFieldsKey{tag: s.GetCoder().GetTag(), typ: t}
+	if v, ok := whereFields.Load(key); ok {
+		return v.([]*whereField), nil
+	}
+
+	desc := types.GetStructDesc(key.tag, t)
+
+	var fields []*whereField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(whereTag)
+		if !ok && sf.Anonymous && sf.PkgPath == "" && indirectType(sf.Type).Kind() == reflect.Struct { // 匿名嵌入结构体展开
+			fields = append(fields, &whereField{index: sf.Index, group: consts.AND})
+			continue
+		}
+
+		if !ok || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+
+		f, err := parseWhereTag(sf, tag, desc)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	whereFields.Store(key, fields)
+	return fields, nil
+}
+
+func parseWhereTag(sf reflect.StructField, tag string, desc *types.StructDesc) (*whereField, error) {
+	f := &whereField{index: sf.Index}
+
+	parts := strings.Split(tag, ",")
+	f.column = strings.TrimSpace(parts[0])
+
+	for _, part := range parts[1:] {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch part {
+		case "omitempty":
+			f.omitEmpty = true
+		case "and", "or", "not":
+			f.group = strings.ToUpper(part)
+		default:
+			op, ok := whereOPs[part]
+			if !ok {
+				return nil, errs.Newf(errs.ErrReqParamInvalid,
+					"field %s has invalid where tag operator %q", sf.Name, part)
+			}
+			f.op = op
+		}
+	}
+
+	if f.group != "" {
+		return f, nil
+	}
+
+	if f.column == "" && desc != nil { // 取 orm 标签列名
+		for _, fd := range desc.Fs {
+			if fd.Name == sf.Name {
+				f.column = fd.Column
+				break
+			}
+		}
+	}
+
+	if f.column == "" {
+		f.column = sf.Name
+	}
+
+	if f.op == "" {
+		f.op = consts.OPEqual
+	}
+
+	return f, nil
+}
+
+// isEmptyValue 零值，以及长度为 0 的切片、数组、map，避免生成 IN () 条件
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"reflect"
+	"testing"
+)
+
+type TestFilterInner struct {
+	Class string `where:"class,omitempty"`
+}
+
+type testFilter struct {
+	TestFilterInner
+	MinAge  *int           `where:"age,gte"`
+	Name    string         `where:"name,like,omitempty"`
+	Genders []int          `where:"gender,omitempty"`
+	Ids     [0]int         `where:"id,omitempty"`
+	Tags    map[string]int `where:"tags,omitempty"`
+	Exclude []int          `where:"score,notin,omitempty"`
+	Keyword struct {
+		Article string `where:"article,like,omitempty"`
+		Title   string `where:"title,like,omitempty"`
+	} `where:",or"`
+	Ignore string
+}
+
+func TestWhereStruct(t *testing.T) {
+	age := 18
+	keyword := testFilter{}
+	keyword.Keyword.Article = "%go%"
+	keyword.Keyword.Title = "%go%"
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		want   Where
+	}{
+		{"nil pointer", (*testFilter)(nil), nil},
+		{"empty", &testFilter{}, nil},
+		{"empty slice and map", &testFilter{Genders: []int{}, Tags: map[string]int{}, Exclude: []int{}}, nil},
+		{"pointer", &testFilter{MinAge: &age}, Where{"age >=": 18}},
+		{"zero pointer", &testFilter{MinAge: new(int)}, Where{"age >=": 0}},
+		{"in", testFilter{Genders: []int{1, 2}, Name: "a%"}, Where{"gender": []int{1, 2}, "name ~": "a%"}},
+		{"not in", testFilter{Exclude: []int{60}}, Where{"score !": []int{60}}},
+		{"embedded", testFilter{TestFilterInner: TestFilterInner{Class: "c1"}}, Where{"class": "c1"}},
+		{"or group", keyword, Where{"OR": Where{"article ~": "%go%", "title ~": "%go%"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery("student").FindAll().WhereStruct(tt.filter)
+			if q.Error != nil {
+				t.Fatalf("where struct error: %v", q.Error)
+			}
+
+			if !reflect.DeepEqual(q.Unit.Where, map[string]interface{}(tt.want)) && !(len(q.Unit.Where) == 0 && tt.want == nil) {
+				t.Fatalf("got %v, want %v", q.Unit.Where, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereStructError(t *testing.T) {
+	type badOP struct {
+		Age int `where:"age,bigger"`
+	}
+
+	tests := []struct {
+		name   string
+		filter interface{}
+	}{
+		{"not struct", 1},
+		{"invalid operator", &badOP{Age: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if q := NewQuery("student").FindAll().WhereStruct(tt.filter); q.Error == nil {
+				t.Fatal("expect where struct error")
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/go-horm/horm/stats"
+)
+
+// flightCall 合并中的请求
+type flightCall struct {
+	done   chan struct{}
+	ctx    *flightContext // 共享请求 context
+	header *proto.ResponseHeader
+	result []byte
+	err    error
+}
+
+// flightGroup 合并并发的相同只读请求，只发起一次网络请求，所有调用方共享返回的原始结果，各自解码到自己的接收者
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do 执行请求，存在相同 key 的请求正在执行时等待其结果。共享请求在只继承值、不继承取消的 context 中执行，
+// 任何调用方（包括发起方）取消都只会让自己返回取消错误，不影响其他调用方。共享请求的截止时间为所有调用方中最早的截止时间，
+// 调用方都没有截止时间时为 defaultTimeout，避免共享请求无限期挂起并一直占用 key。
+func (g *flightGroup) do(ctx context.Context, name, key string,
+	fn func(ctx context.Context) (*proto.ResponseHeader, []byte, error)) (*proto.ResponseHeader, []byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		if deadline, ok := ctx.Deadline(); ok {
+			c.ctx.shorten(deadline)
+		}
+		g.mu.Unlock()
+		stats.AddCounter(stats.CoalescedTotal, stats.Labels{"client": name}, 1)
+		return c.wait(ctx)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout * time.Millisecond)
+	}
+
+	c := &flightCall{done: make(chan struct{}), ctx: newFlightContext(ctx, deadline)}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		c.header, c.result, c.err = fn(c.ctx)
+		c.ctx.stop()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	return c.wait(ctx)
+}
+
+// wait 等待共享请求的结果，ctx 结束则直接返回
+func (c *flightCall) wait(ctx context.Context) (*proto.ResponseHeader, []byte, error) {
+	select {
+	case <-c.done:
+		return c.header, c.result, c.err
+	case <-ctx.Done():
+		return nil, nil, ctxError(ctx, "coalesced request")
+	}
+}
+
+// flightContext 共享请求 context，只继承发起方 context 的值，截止时间可以被后加入的调用方缩短
+type flightContext struct {
+	parent   context.Context
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	done     chan struct{}
+	err      error
+}
+
+func newFlightContext(parent context.Context, deadline time.Time) *flightContext {
+	c := &flightContext{parent: parent, deadline: deadline, done: make(chan struct{})}
+	c.timer = time.AfterFunc(time.Until(deadline), c.expire)
+	return c
+}
+
+func (c *flightContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, true
+}
+
+func (c *flightContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *flightContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *flightContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// shorten 截止时间早于当前截止时间时缩短
+func (c *flightContext) shorten(deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil || !deadline.Before(c.deadline) {
+		return
+	}
+
+	c.deadline = deadline
+	c.timer.Reset(time.Until(deadline))
+}
+
+func (c *flightContext) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = context.DeadlineExceeded
+		close(c.done)
+	}
+}
+
+// stop 共享请求结束，释放定时器
+func (c *flightContext) stop() {
+	c.timer.Stop()
+}
+
+// requestKey 请求 key，相同客户端、空间、目标地址、调用方身份（appid、caller、secret、token）、metadata、
+// 查询模式以及请求体的请求才会被合并、共享缓存，不同身份的调用方不会拿到彼此的结果
+func requestKey(name string, opts *Options, mode uint32, body []byte) string {
+	caller := opts.Caller
+	if caller == "" {
+		caller = opts.Name
+	}
+
+	credential := sha256.Sum256([]byte(opts.Secret + "\x00" + opts.Token))
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString("|" + strconv.Itoa(opts.WorkspaceID))
+	sb.WriteString("|" + opts.Target)
+	sb.WriteString("|" + strconv.FormatUint(opts.Appid, 10))
+	sb.WriteString("|" + caller)
+	sb.WriteString("|" + hex.EncodeToString(credential[:]))
+
+	keys := make([]string, 0, len(opts.Metadata))
+	for k := range opts.Metadata {
+		if k != "traceparent" && k != "tracestate" { // trace-context 每个请求都不同，不参与 key
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sb.WriteString("|" + strconv.Quote(k) + "=" + strconv.Quote(opts.Metadata[k]))
+	}
+
+	sb.WriteString("|" + strconv.FormatUint(uint64(mode), 10))
+	sb.WriteString("|")
+	sb.Write(body)
+	return sb.String()
+}
+
+// readOnly 是否所有执行单元都是只读操作，直接输入的查询语句、字节码无法判断是否只读，加锁查询需要独立执行，均视为非只读
+func readOnly(q *Query) bool {
+	ret := true
+	walkQuery(q, func(s *Query) {
+		if consts.OpType(s.Unit.Op) != consts.OpTypeRead || s.Unit.Query != "" ||
+			len(s.Unit.Bytes) > 0 || s.Unit.Params[paramLock] != nil {
+			ret = false
+		}
+	})
+	return ret
+}
+
+// ctxError 等待结果期间 ctx 结束，返回取消或超时错误
+func ctxError(ctx context.Context, what string) error {
+	if ctx.Err() == context.Canceled {
+		return errs.New(errs.ErrClientCanceled, what+" canceled: "+ctx.Err().Error())
+	}
+	return errs.New(errs.ErrClientTimeout, what+" timeout: "+ctx.Err().Error())
+}
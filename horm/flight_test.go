@@ -0,0 +1,248 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+func TestRequestKeyIsolation(t *testing.T) {
+	base := func() *Options {
+		return &Options{
+			WorkspaceID: 31,
+			Target:      "ip://127.0.0.1:8180",
+			Name:        "ws_test.app1.server1.service1",
+			Caller:      "app1.server1.service1",
+			Appid:       10002,
+			Secret:      "S959223456",
+			Token:       "QUIs32ODQUIs32OD",
+			Metadata:    map[string]string{"tenant": "t1"},
+		}
+	}
+
+	body := []byte(`[{"name":"student","op":"find"}]`)
+	key := requestKey("cli", base(), 1, body)
+
+	tests := []struct {
+		name   string
+		modify func(o *Options)
+		same   bool
+	}{
+		{"same options", func(o *Options) {}, true},
+		{"appid", func(o *Options) { o.Appid = 10003 }, false},
+		{"secret", func(o *Options) { o.Secret = "S499721834" }, false},
+		{"token", func(o *Options) { o.Token = "other" }, false},
+		{"caller", func(o *Options) { o.Caller = "app2.server2.service2" }, false},
+		{"caller from name", func(o *Options) { o.Caller = ""; o.Name = "app1.server1.service1" }, true},
+		{"workspace", func(o *Options) { o.WorkspaceID = 32 }, false},
+		{"target", func(o *Options) { o.Target = "ip://127.0.0.1:8181" }, false},
+		{"metadata value", func(o *Options) { o.Metadata["tenant"] = "t2" }, false},
+		{"metadata key", func(o *Options) { o.Metadata["region"] = "gz" }, false},
+		{"no metadata", func(o *Options) { o.Metadata = nil }, false},
+		{"trace-context", func(o *Options) { o.Metadata["traceparent"] = "00-abc-def-01" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := base()
+			tt.modify(opts)
+
+			if got := requestKey("cli", opts, 1, body) == key; got != tt.same {
+				t.Fatalf("same key = %v, want %v", got, tt.same)
+			}
+		})
+	}
+
+	if requestKey("cli", base(), 2, body) == key {
+		t.Fatalf("different query mode got same key")
+	}
+
+	if requestKey("other", base(), 1, body) == key {
+		t.Fatalf("different client got same key")
+	}
+}
+
+func TestFlightGroupLeaderCanceled(t *testing.T) {
+	g := &flightGroup{}
+	release := make(chan struct{})
+
+	var calls int
+	var mu sync.Mutex
+	fn := func(ctx context.Context) (*proto.ResponseHeader, []byte, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		<-release
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return &proto.ResponseHeader{RequestId: 1}, []byte("result"), nil
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(leaderCtx, "cli", "key", fn)
+		leaderErr <- err
+	}()
+
+	// 等待发起方注册共享请求
+	for {
+		g.mu.Lock()
+		n := len(g.calls)
+		g.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	type ret struct {
+		result []byte
+		err    error
+	}
+
+	followerCtx, followerCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer followerCancel()
+
+	followerRet := make(chan ret, 1)
+	go func() {
+		_, result, err := g.do(followerCtx, "cli", "key", fn)
+		followerRet <- ret{result, err}
+	}()
+
+	// 等待跟随方加入，加入时共享请求的截止时间缩短为跟随方的截止时间
+	for {
+		g.mu.Lock()
+		d, _ := g.calls["key"].ctx.Deadline()
+		g.mu.Unlock()
+		if time.Until(d) <= 30*time.Second {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	var e *errs.Error
+	if err := <-leaderErr; !errors.As(err, &e) || e.Code != errs.ErrClientCanceled {
+		t.Fatalf("leader got %v, want canceled error", err)
+	}
+
+	close(release)
+
+	r := <-followerRet
+	if r.err != nil || string(r.result) != "result" {
+		t.Fatalf("follower got %s, %v, want result of shared call", r.result, r.err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Fatalf("shared call executed %d times, want 1", calls)
+	}
+}
+
+func TestFlightGroupFollowerTimeout(t *testing.T) {
+	g := &flightGroup{}
+	release := make(chan struct{})
+	defer close(release)
+
+	fn := func(ctx context.Context) (*proto.ResponseHeader, []byte, error) {
+		<-release
+		return nil, nil, nil
+	}
+
+	go func() { _, _, _ = g.do(context.Background(), "cli", "key", fn) }()
+
+	for {
+		g.mu.Lock()
+		n := len(g.calls)
+		g.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var e *errs.Error
+	if _, _, err := g.do(ctx, "cli", "key", fn); !errors.As(err, &e) || e.Code != errs.ErrClientTimeout {
+		t.Fatalf("follower got %v, want timeout error", err)
+	}
+}
+
+func TestFlightGroupDeadline(t *testing.T) {
+	g := &flightGroup{}
+	deadlines := make(chan time.Time, 1)
+
+	fn := func(ctx context.Context) (*proto.ResponseHeader, []byte, error) {
+		d, _ := ctx.Deadline()
+		deadlines <- d
+
+		<-ctx.Done() // 模拟挂起的请求，直到共享请求超时
+		return nil, nil, ctxError(ctx, "request")
+	}
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(context.Background(), "cli", "key", fn)
+		leaderErr <- err
+	}()
+
+	d := <-deadlines
+	if left := time.Until(d); left <= 0 || left > defaultTimeout*time.Millisecond {
+		t.Fatalf("shared call deadline in %v, want default timeout", left)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var e *errs.Error
+	if _, _, err := g.do(ctx, "cli", "key", fn); !errors.As(err, &e) || e.Code != errs.ErrClientTimeout {
+		t.Fatalf("waiter got %v, want timeout error", err)
+	}
+
+	if cost := time.Since(start); cost > 500*time.Millisecond {
+		t.Fatalf("waiter returned after %v, want about 20ms", cost)
+	}
+
+	select { // 共享请求被缩短到最早的截止时间，不再占用 key
+	case err := <-leaderErr:
+		if !errors.As(err, &e) || e.Code != errs.ErrClientTimeout {
+			t.Fatalf("leader got %v, want timeout error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("shared call is not bounded by the earliest waiter deadline")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.calls) != 0 {
+		t.Fatalf("key is still claimed by %d calls", len(g.calls))
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+)
+
+// Future 异步执行结果
+type Future struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	isNil  bool
+	err    error
+}
+
+// ExecAsync 异步执行单个操作单元，结果接收者在 Future 完成后才可以读取
+func (s *Query) ExecAsync(ctx context.Context, retReceiver ...interface{}) *Future {
+	return goFuture(ctx, func(ctx context.Context) (bool, error) {
+		return s.Exec(ctx, retReceiver...)
+	})
+}
+
+// PExecAsync 异步并行执行多个操作单元
+func (s *Query) PExecAsync(ctx context.Context) *Future {
+	return goFuture(ctx, func(ctx context.Context) (bool, error) {
+		return false, s.PExec(ctx)
+	})
+}
+
+// CompExecAsync 异步执行复合查询
+func (s *Query) CompExecAsync(ctx context.Context, retReceiver interface{}) *Future {
+	return goFuture(ctx, func(ctx context.Context) (bool, error) {
+		return false, s.CompExec(ctx, retReceiver)
+	})
+}
+
+func goFuture(ctx context.Context, fn func(ctx context.Context) (bool, error)) *Future {
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Future{done: make(chan struct{}), cancel: cancel}
+
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		f.isNil, f.err = fn(ctx)
+	}()
+
+	return f
+}
+
+// Wait 等待执行完成，返回与 Exec 相同的 isNil 与 error
+func (f *Future) Wait() (isNil bool, err error) {
+	<-f.done
+	return f.isNil, f.err
+}
+
+// Done 执行完成时关闭的 channel
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel 取消执行，未完成的执行会返回 errors.Is(err, ErrCanceled) 等取消错误
+func (f *Future) Cancel() {
+	f.cancel()
+}
+
+// Err 执行错误，需在执行完成后调用
+func (f *Future) Err() error {
+	<-f.done
+	return f.err
+}
+
+// WaitAll 等待所有 Future 执行完成，返回第一个（按传入顺序）错误，
+// ctx 结束时取消所有未完成的执行并返回 ctx.Err()。
+func WaitAll(ctx context.Context, futures ...*Future) error {
+	for _, f := range futures {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			for _, f := range futures {
+				f.Cancel()
+			}
+			return ctx.Err()
+		}
+	}
+
+	for _, f := range futures {
+		if f.err != nil {
+			return f.err
+		}
+	}
+
+	return nil
+}
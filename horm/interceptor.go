@@ -0,0 +1,43 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+
+	"github.com/horm-database/common/proto"
+)
+
+// Invoker 发起请求，返回服务端的返回头与返回体
+type Invoker func(ctx context.Context,
+	head *proto.RequestHeader, reqBody []byte) (*proto.ResponseHeader, []byte, error)
+
+// Interceptor 请求拦截器，可以在请求前后做录制、回放、mock 等处理，调用 next 继续请求，不调用则直接返回。
+// 请求头已签名，每次请求（包括重试）都会经过拦截器。
+type Interceptor func(ctx context.Context, head *proto.RequestHeader,
+	reqBody []byte, next Invoker) (*proto.ResponseHeader, []byte, error)
+
+// chainInterceptors 将拦截器串联，先添加的拦截器先执行
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context,
+			head *proto.RequestHeader, reqBody []byte) (*proto.ResponseHeader, []byte, error) {
+			return interceptor(ctx, head, reqBody, next)
+		}
+	}
+
+	return invoker
+}
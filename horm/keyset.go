@@ -0,0 +1,354 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+)
+
+// 游标分页参数
+const (
+	paramSearchAfter = "search_after" // elastic search_after
+
+	keysetAfter  = 1
+	keysetBefore = 2
+)
+
+var (
+	cursorLock = new(sync.RWMutex)
+	cursorKey  []byte
+)
+
+// SetCursorKey 设置游标签名密钥，游标分页前必须设置，未设置时 KeysetExec 返回错误。
+// 多实例部署时所有实例需设置相同的密钥，游标才能跨实例使用，密钥泄露时游标可以被伪造。
+func SetCursorKey(key []byte) {
+	cursorLock.Lock()
+	cursorKey = append([]byte(nil), key...)
+	cursorLock.Unlock()
+}
+
+// KeysetPage 游标分页信息
+type KeysetPage struct {
+	Next    string `json:"next,omitempty"` // 下一页游标，传给 After
+	Prev    string `json:"prev,omitempty"` // 上一页游标，传给 Before
+	HasMore bool   `json:"has_more"`       // 当前方向上是否还有更多数据
+	Size    int    `json:"size"`           // 本页记录数
+}
+
+// 游标分页信息在 proto.Detail.Extras 中的 key
+const (
+	DetailNext    = "next"     // 下一页游标
+	DetailPrev    = "prev"     // 上一页游标
+	DetailHasMore = "has_more" // 是否还有更多数据
+)
+
+// fillDetail 将游标分页信息写入查询细节，与分页返回的 proto.Detail 接收方式一致
+func (p *KeysetPage) fillDetail(detail *proto.Detail) {
+	detail.Size = p.Size
+	if detail.Extras == nil {
+		detail.Extras = map[string]interface{}{}
+	}
+
+	detail.Extras[DetailNext] = p.Next
+	detail.Extras[DetailPrev] = p.Prev
+	detail.Extras[DetailHasMore] = p.HasMore
+}
+
+// cursor 游标内容，记录排序列及边界记录的排序列值
+type cursor struct {
+	Order  []string      `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+// After 游标分页，查询 cursor 之后的一页数据，cursor 为空时查询第一页，需配合 Order、Limit 与 KeysetExec 使用，
+// mysql、postgresql、clickhouse 根据排序列生成 (a > ?) OR (a = ? AND b > ?) 条件，elastic 使用 search_after。
+// 排序列组合必须唯一（例如最后加上主键），否则可能漏数据；排序列不能为 NULL，NULL 无法比较大小，
+// 边界记录的排序列为 NULL 时 KeysetExec 返回错误。游标在 KeysetExec 时解析，与 Order 的调用顺序无关。
+func (s *Query) After(cursor string) *Query {
+	s.keysetDir, s.keysetCursor = keysetAfter, cursor
+	return s
+}
+
+// Before 游标分页，查询 cursor 之前的一页数据，需配合 Order、Limit 与 KeysetExec 使用
+func (s *Query) Before(cursor string) *Query {
+	s.keysetDir, s.keysetCursor = keysetBefore, cursor
+	return s
+}
+
+// seek 在语句副本上设置游标条件，mysql、postgresql、clickhouse 为 seek 条件，elastic 为 search_after
+func (s *Query) seek(token string, before bool) error {
+	c, err := decodeCursor(token)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(c.Order, ",") != strings.Join(s.Unit.Order, ",") || len(c.Values) != len(c.Order) {
+		return errs.New(errs.ErrReqParamInvalid, "cursor does not match query order")
+	}
+
+	for i, v := range c.Values {
+		if v == nil { // col > NULL 恒为假，无法翻页
+			return errs.Newf(errs.ErrReqParamInvalid, "cursor order column %s is null", orderColumn(c.Order[i]))
+		}
+	}
+
+	es, err := s.isElastic()
+	if err != nil {
+		return err
+	}
+
+	if es {
+		s.SetParam(paramSearchAfter, c.Values)
+		return nil
+	}
+
+	s.WhereExpr(seekCond(c, before))
+	return s.Error
+}
+
+// seekCond 生成 (a > va) OR (a = va AND b > vb) ... 形式的条件，降序列比较方向相反，before 时整体反向
+func seekCond(c *cursor, before bool) *Cond {
+	ors := make([]*Cond, 0, len(c.Order))
+
+	for i, order := range c.Order {
+		ands := make([]*Cond, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, Col(orderColumn(c.Order[j])).Eq(c.Values[j]))
+		}
+
+		col := Col(orderColumn(order))
+		if isDesc(order) != before {
+			ands = append(ands, col.Lt(c.Values[i]))
+		} else {
+			ands = append(ands, col.Gt(c.Values[i]))
+		}
+
+		ors = append(ors, And(ands...))
+	}
+
+	return Or(ors...)
+}
+
+// KeysetExec 执行游标分页查询，结果解码到 retReceiver（切片指针），返回上一页、下一页游标，不会修改原语句。
+// 与分页返回一致，第一个接收者为 *proto.Detail 时，游标写入 Extras 的 next、prev、has_more，结果解码到第二个接收者，例如：
+// q.KeysetExec(ctx, &detail, &result)
+// 会多查询一条记录判断是否还有更多数据，Before 查询时按反向排序查询后再翻转为正常顺序。
+// 需要先通过 SetCursorKey 设置游标签名密钥，以及在 orm.yaml 中配置执行单元的数据库类型。
+func (s *Query) KeysetExec(ctx context.Context, retReceiver ...interface{}) (*KeysetPage, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+
+	if !hasCursorKey() {
+		return nil, errs.New(errs.ErrReqParamInvalid, "keyset pagination requires cursor key, call horm.SetCursorKey first")
+	}
+
+	order := s.Unit.Order
+	if len(order) == 0 {
+		return nil, errs.New(errs.ErrReqParamInvalid, "keyset pagination requires order columns")
+	}
+
+	before := s.keysetDir == keysetBefore
+
+	size := s.Unit.Size
+	if size <= 0 {
+		size = 100
+	}
+
+	q := s.clone()
+	if q.Unit.Op == "" {
+		q.FindAll()
+	}
+
+	if _, err := q.isElastic(); err != nil { // 校验数据库类型，第一页同样需要
+		return nil, err
+	}
+
+	if s.keysetCursor != "" {
+		if err := q.seek(s.keysetCursor, before); err != nil {
+			return nil, err
+		}
+	}
+
+	q.Unit.Size = size + 1
+	q.Unit.Page = 0
+	q.Unit.From = 0
+
+	if len(q.Unit.Column) > 0 { // 确保返回排序列
+		for _, o := range order {
+			if !hasColumn(q.Unit.Column, orderColumn(o)) {
+				q.Unit.Column = append(q.Unit.Column, orderColumn(o))
+			}
+		}
+	}
+
+	if before { // 反向排序查询
+		q.Unit.Order = reverseOrder(order)
+	}
+
+	var rows []map[string]interface{}
+	_, err := q.Exec(ctx, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &KeysetPage{HasMore: len(rows) > size}
+	if page.HasMore {
+		rows = rows[:size]
+	}
+
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page.Size = len(rows)
+	if len(rows) > 0 {
+		if page.Next, err = encodeCursor(order, rows[len(rows)-1]); err != nil {
+			return nil, err
+		}
+		if page.Prev, err = encodeCursor(order, rows[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(retReceiver) > 0 {
+		if detail, ok := retReceiver[0].(*proto.Detail); ok {
+			page.fillDetail(detail)
+			retReceiver = retReceiver[1:]
+		}
+	}
+
+	if len(retReceiver) == 0 {
+		return page, nil
+	}
+
+	values := make([]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row
+	}
+
+	if err = q.GetCoder().Decode(q.ResultType, values, retReceiver[:1]); err != nil {
+		return nil, errs.Newf(errs.ErrClientDecode, "[request_id=%d] keyset result decode error: %v", q.RequestID, err)
+	}
+
+	return page, nil
+}
+
+// encodeCursor 生成签名游标 base64(payload).base64(hmac)
+func encodeCursor(order []string, row map[string]interface{}) (string, error) {
+	c := cursor{Order: order, Values: make([]interface{}, len(order))}
+	for i, o := range order {
+		v, ok := row[orderColumn(o)]
+		if !ok {
+			return "", errs.Newf(errs.ErrReqParamInvalid, "keyset result has no order column %s", orderColumn(o))
+		}
+
+		if v == nil {
+			return "", errs.Newf(errs.ErrReqParamInvalid,
+				"keyset order column %s is null, order columns must be not null", orderColumn(o))
+		}
+
+		c.Values[i] = v
+	}
+
+	payload, err := json.Api.Marshal(&c)
+	if err != nil {
+		return "", errs.New(errs.ErrClientEncode, "cursor marshal error: "+err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCursor(payload)), nil
+}
+
+// decodeCursor 校验签名并解析游标
+func decodeCursor(token string) (*cursor, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return nil, errs.New(errs.ErrReqParamInvalid, "invalid cursor")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errs.New(errs.ErrReqParamInvalid, "invalid cursor")
+	}
+
+	sign, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sign, signCursor(payload)) {
+		return nil, errs.New(errs.ErrReqParamInvalid, "invalid cursor signature")
+	}
+
+	c := cursor{}
+	if err = json.Api.Unmarshal(payload, &c); err != nil {
+		return nil, errs.New(errs.ErrReqParamInvalid, "invalid cursor: "+err.Error())
+	}
+
+	return &c, nil
+}
+
+func hasCursorKey() bool {
+	cursorLock.RLock()
+	defer cursorLock.RUnlock()
+	return len(cursorKey) > 0
+}
+
+func signCursor(payload []byte) []byte {
+	cursorLock.RLock()
+	mac := hmac.New(sha256.New, cursorKey)
+	cursorLock.RUnlock()
+
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// orderColumn 排序列名，去掉 +、- 前缀
+func orderColumn(order string) string {
+	return strings.TrimLeft(strings.TrimSpace(order), "+-")
+}
+
+func isDesc(order string) bool {
+	return strings.HasPrefix(strings.TrimSpace(order), "-")
+}
+
+func reverseOrder(orders []string) []string {
+	ret := make([]string, len(orders))
+	for i, order := range orders {
+		if isDesc(order) {
+			ret[i] = "+" + orderColumn(order)
+		} else {
+			ret[i] = "-" + orderColumn(order)
+		}
+	}
+	return ret
+}
+
+func hasColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column || c == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+// withCursorKey 设置测试游标签名密钥，返回恢复函数
+func withCursorKey(key string) func() {
+	cursorLock.RLock()
+	old := cursorKey
+	cursorLock.RUnlock()
+
+	SetCursorKey([]byte(key))
+	return func() { SetCursorKey(old) }
+}
+
+func TestSeekCond(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  []string
+		values []interface{}
+		before bool
+		want   Where
+	}{
+		{"single asc after", []string{"+id"}, []interface{}{5}, false, Where{"id >": 5}},
+		{"single asc before", []string{"id"}, []interface{}{5}, true, Where{"id <": 5}},
+		{"single desc after", []string{"-id"}, []interface{}{5}, false, Where{"id <": 5}},
+		{"single desc before", []string{"-id"}, []interface{}{5}, true, Where{"id >": 5}},
+		{"desc and asc after", []string{"-created_at", "+id"}, []interface{}{"t", 5}, false,
+			Where{"OR": Where{"AND": Where{"created_at <": "t"}, "AND #2": Where{"created_at": "t", "id >": 5}}}},
+		{"three columns before", []string{"a", "b", "c"}, []interface{}{1, 2, 3}, true,
+			Where{"OR": Where{"AND": Where{"a <": 1}, "AND #2": Where{"a": 1, "b <": 2},
+				"AND #3": Where{"a": 1, "b": 2, "c <": 3}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := seekCond(&cursor{Order: tt.order, Values: tt.values}, tt.before).Where()
+			if err != nil {
+				t.Fatalf("seek condition error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorSign(t *testing.T) {
+	defer withCursorKey("key1")()
+
+	token, err := encodeCursor([]string{"-score", "+id"}, map[string]interface{}{"score": 90, "id": 7})
+	if err != nil {
+		t.Fatalf("encode cursor error: %v", err)
+	}
+
+	c, err := decodeCursor(token)
+	if err != nil {
+		t.Fatalf("decode cursor error: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Order, []string{"-score", "+id"}) || len(c.Values) != 2 {
+		t.Fatalf("decoded cursor %+v", c)
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	forged, _ := encodeCursor([]string{"-score", "+id"}, map[string]interface{}{"score": 0, "id": 0})
+
+	tests := []struct {
+		name  string
+		token string
+		key   string
+	}{
+		{"no signature", parts[0], "key1"},
+		{"bad base64", "!." + parts[1], "key1"},
+		{"payload replaced", strings.SplitN(forged, ".", 2)[0] + "." + parts[1], "key1"},
+		{"signature truncated", parts[0] + "." + parts[1][:10], "key1"},
+		{"other key", token, "key2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetCursorKey([]byte(tt.key))
+			if _, err := decodeCursor(tt.token); errs.Code(err) != errs.ErrReqParamInvalid {
+				t.Fatalf("decode cursor error %v, want invalid", err)
+			}
+		})
+	}
+}
+
+func TestKeysetExec(t *testing.T) {
+	defer withCursorKey("key1")()
+
+	cursor, _ := encodeCursor([]string{"-score", "+id"}, map[string]interface{}{"score": 90, "id": 7})
+	rows := `[{"id":8,"score":90},{"id":9,"score":80},{"id":10,"score":70}]`
+
+	tests := []struct {
+		name   string
+		table  string
+		query  func(q *Query) *Query
+		order  []string
+		where  Where
+		params map[string]interface{}
+		next   int
+	}{
+		{"first page", "mysql_test", func(q *Query) *Query {
+			return q.Order("-score", "+id").After("")
+		}, []string{"-score", "+id"}, Where{"class": "c1"}, nil, 9},
+		{"after before order", "mysql_test", func(q *Query) *Query {
+			return q.After(cursor).Order("-score", "+id")
+		}, []string{"-score", "+id"}, Where{"class": "c1", "OR": map[string]interface{}{
+			"AND": map[string]interface{}{"score <": float64(90)}, "AND #2": map[string]interface{}{"score": float64(90), "id >": float64(7)},
+		}}, nil, 9},
+		{"before", "mysql_test", func(q *Query) *Query {
+			return q.Order("-score", "+id").Before(cursor)
+		}, []string{"+score", "-id"}, Where{"class": "c1", "OR": map[string]interface{}{
+			"AND": map[string]interface{}{"score >": float64(90)}, "AND #2": map[string]interface{}{"score": float64(90), "id <": float64(7)},
+		}}, nil, 8},
+		{"elastic", "es_test", func(q *Query) *Query {
+			return q.Order("-score", "+id").After(cursor)
+		}, []string{"-score", "+id"}, Where{"class": "c1"},
+			map[string]interface{}{paramSearchAfter: []interface{}{float64(90), float64(7)}}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var unit *proto.Unit
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, rows))))
+
+			q := tt.query(NewQuery(tt.table).FindAll(Where{"class": "c1"}).Column("id").Limit(2).WithClient(c))
+			origin := *q.Unit
+
+			var result []map[string]interface{}
+			page, err := q.KeysetExec(context.Background(), &result)
+			if err != nil {
+				t.Fatalf("keyset exec error: %v", err)
+			}
+
+			if !reflect.DeepEqual(*q.Unit, origin) {
+				t.Fatalf("caller query modified: %+v", q.Unit)
+			}
+
+			if unit.Size != 3 || !reflect.DeepEqual(unit.Order, tt.order) || !reflect.DeepEqual(unit.Column, []string{"id", "score"}) {
+				t.Fatalf("keyset unit size %d order %v column %v", unit.Size, unit.Order, unit.Column)
+			}
+
+			if !reflect.DeepEqual(unit.Where, map[string]interface{}(tt.where)) {
+				t.Fatalf("keyset unit where %v, want %v", unit.Where, tt.where)
+			}
+
+			if !reflect.DeepEqual(unit.Params, tt.params) {
+				t.Fatalf("keyset unit params %v, want %v", unit.Params, tt.params)
+			}
+
+			if !page.HasMore || page.Size != 2 || len(result) != 2 {
+				t.Fatalf("keyset page %+v result %v", page, result)
+			}
+
+			next, err := decodeCursor(page.Next)
+			if err != nil || fmt.Sprint(next.Values[1]) != fmt.Sprint(tt.next) {
+				t.Fatalf("next cursor %+v error %v, want id %d", next, err, tt.next)
+			}
+		})
+	}
+}
+
+func TestKeysetExecError(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		query *Query
+		code  int
+	}{
+		{"no cursor key", "", NewQuery("mysql_test").FindAll().Order("+id").After(""), errs.ErrReqParamInvalid},
+		{"no order", "key1", NewQuery("mysql_test").FindAll().After(""), errs.ErrReqParamInvalid},
+		{"unknown db type", "key1", NewQuery("student").FindAll().Order("+id").After(""), errs.ErrDBConfigNotFound},
+		{"invalid cursor", "key1", NewQuery("mysql_test").FindAll().Order("+id").After("abc"), errs.ErrReqParamInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer withCursorKey(tt.key)()
+
+			var calls int32
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler)))
+
+			var result []map[string]interface{}
+			if _, err := tt.query.WithClient(c).KeysetExec(context.Background(), &result); errs.Code(err) != tt.code {
+				t.Fatalf("keyset exec error %v, want code %d", err, tt.code)
+			}
+
+			if calls != 0 {
+				t.Fatalf("requests %d, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestKeysetCursorOrderMismatch(t *testing.T) {
+	defer withCursorKey("key1")()
+
+	cursor, _ := encodeCursor([]string{"+id"}, map[string]interface{}{"id": 7})
+
+	var calls int32
+	c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler)))
+
+	var result []map[string]interface{}
+	_, err := NewQuery("mysql_test").FindAll().Order("-id").After(cursor).WithClient(c).KeysetExec(context.Background(), &result)
+	if errs.Code(err) != errs.ErrReqParamInvalid || calls != 0 {
+		t.Fatalf("keyset exec error %v requests %d, want order mismatch", err, calls)
+	}
+}
+
+func TestKeysetExecDetail(t *testing.T) {
+	defer withCursorKey("key1")()
+
+	var calls int32
+	var unit *proto.Unit
+	rows := `[{"id":8,"score":90},{"id":9,"score":80},{"id":10,"score":70}]`
+	c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, rows))))
+
+	detail := proto.Detail{}
+	var result []map[string]interface{}
+	page, err := NewQuery("mysql_test").FindAll().Order("-score", "+id").Limit(2).After("").
+		WithClient(c).KeysetExec(context.Background(), &detail, &result)
+	if err != nil {
+		t.Fatalf("keyset exec error: %v", err)
+	}
+
+	if detail.Size != 2 || len(result) != 2 || detail.Extras[DetailHasMore] != true ||
+		detail.Extras[DetailNext] != page.Next || detail.Extras[DetailPrev] != page.Prev || page.Next == "" {
+		t.Fatalf("keyset detail %+v page %+v result %v", detail, page, result)
+	}
+
+	// 仅接收查询细节
+	detail = proto.Detail{}
+	if _, err = NewQuery("mysql_test").FindAll().Order("+id").Limit(5).
+		WithClient(c).KeysetExec(context.Background(), &detail); err != nil {
+		t.Fatalf("keyset exec error: %v", err)
+	}
+
+	if detail.Size != 3 || detail.Extras[DetailHasMore] != false {
+		t.Fatalf("keyset detail %+v", detail)
+	}
+}
+
+func TestKeysetNullOrder(t *testing.T) {
+	defer withCursorKey("key1")()
+
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{"null boundary", `[{"id":8,"score":90},{"id":9,"score":null}]`},
+		{"missing column", `[{"id":8,"score":90},{"id":9}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var unit *proto.Unit
+			c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, tt.rows))))
+
+			var result []map[string]interface{}
+			_, err := NewQuery("mysql_test").FindAll().Order("-score", "+id").Limit(5).
+				WithClient(c).KeysetExec(context.Background(), &result)
+			if errs.Code(err) != errs.ErrReqParamInvalid || calls != 1 {
+				t.Fatalf("keyset exec error %v requests %d, want null order error", err, calls)
+			}
+		})
+	}
+
+	// 签名合法但包含 NULL 的游标不会生成 score < NULL 条件
+	payload := []byte(`{"o":["-score","+id"],"v":[null,7]}`)
+	cursor := base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCursor(payload))
+
+	var calls int32
+	c := NewClient("ws_test.app1.server1.service1", WithInterceptor(mockInterceptor(&calls, okHandler)))
+
+	var result []map[string]interface{}
+	_, err := NewQuery("mysql_test").FindAll().Order("-score", "+id").After(cursor).
+		WithClient(c).KeysetExec(context.Background(), &result)
+	if errs.Code(err) != errs.ErrReqParamInvalid || calls != 0 {
+		t.Fatalf("keyset exec error %v requests %d, want null cursor error", err, calls)
+	}
+
+	if _, err = encodeCursor([]string{"+id"}, map[string]interface{}{"id": nil}); errs.Code(err) != errs.ErrReqParamInvalid {
+		t.Fatalf("encode null cursor error %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/log"
+	"github.com/horm-database/common/util"
+)
+
+const maskValue = "******"
+
+// defaultMaskFields 默认脱敏字段，字段名包含以下关键字（不区分大小写）时，日志中的值会被替换为 ******
+var defaultMaskFields = []string{"password", "passwd", "pwd", "secret", "token"}
+
+// unitDBConfig 执行单元对应的数据库配置，配置名为执行单元名去掉别名，例如 student(s1) 取 student 的配置
+func unitDBConfig(q *Query) (*dbConfig, error) {
+	name, _ := util.Alias(q.Unit.Name)
+	return GetDBConfig(name)
+}
+
+// logUnit 按数据库配置打印执行单元的慢查询、错误、debug 日志
+func logUnit(ctx context.Context, q *Query, cost time.Duration, err error, result []byte) {
+	dbCfg, _ := unitDBConfig(q)
+	if dbCfg == nil { // 未配置的数据库只打印错误日志
+		dbCfg = &dbConfig{}
+	}
+
+	if err != nil {
+		if dbCfg.OmitError != 1 {
+			log.Errorf(ctx, errCode(err), "horm unit [%s] %s error: %v, shard=%v, where=%s, cost=%s",
+				q.Unit.Name, q.Unit.Op, err, q.Unit.Shard, maskJSON(q.Unit.Where, dbCfg.MaskFields), cost)
+		}
+	} else if dbCfg.WarnTimeout > 0 && cost >= time.Duration(dbCfg.WarnTimeout)*time.Millisecond {
+		log.Warnf(ctx, "horm unit [%s] %s slow query, shard=%v, where=%s, cost=%s",
+			q.Unit.Name, q.Unit.Op, q.Unit.Shard, maskJSON(q.Unit.Where, dbCfg.MaskFields), cost)
+	}
+
+	if dbCfg.Debug == 1 {
+		log.Debugf(ctx, "horm unit [%s] %s request_id=%d, request=%s, response=%s, error=%v, cost=%s",
+			q.Unit.Name, q.Unit.Op, q.RequestID, maskJSON(q.Unit, dbCfg.MaskFields),
+			maskResult(result, dbCfg.MaskFields), err, cost)
+	}
+}
+
+// maskResult 返回结果脱敏，无法解析的结果原样返回
+func maskResult(result []byte, maskFields []string) string {
+	if len(result) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Api.Unmarshal(result, &v); err != nil {
+		return string(result)
+	}
+
+	return maskJSON(v, maskFields)
+}
+
+// maskJSON 将 v 序列化为 json，并对敏感字段脱敏
+func maskJSON(v interface{}, maskFields []string) string {
+	if v == nil {
+		return ""
+	}
+
+	buf, err := json.Api.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+
+	var m interface{}
+	if err = json.Api.Unmarshal(buf, &m); err != nil {
+		return string(buf)
+	}
+
+	buf, err = json.Api.Marshal(mask(m, maskFields))
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(buf)
+}
+
+func mask(v interface{}, maskFields []string) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, val := range vv {
+			if isMaskField(k, maskFields) {
+				vv[k] = maskValue
+			} else {
+				vv[k] = mask(val, maskFields)
+			}
+		}
+	case []interface{}:
+		for i, val := range vv {
+			vv[i] = mask(val, maskFields)
+		}
+	}
+
+	return v
+}
+
+func isMaskField(key string, maskFields []string) bool {
+	key = strings.ToLower(key)
+
+	// where 条件 key 可能带有操作符，例如 password !=
+	if i := strings.IndexAny(key, " !<>=~?*("); i > 0 {
+		key = key[:i]
+	}
+
+	for _, f := range maskFields {
+		if key == strings.ToLower(f) {
+			return true
+		}
+	}
+
+	for _, f := range defaultMaskFields {
+		if strings.Contains(key, f) {
+			return true
+		}
+	}
+
+	return false
+}
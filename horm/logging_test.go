@@ -0,0 +1,57 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogUnitMaskFields(t *testing.T) {
+	where := Where{"id_card": "110101199003077777", "name": "bob", "password !=": "p@ss"}
+
+	tests := []struct {
+		name   string
+		unit   string
+		masked []string
+		plain  []string
+	}{
+		{"unit", "mysql_test", []string{"110101199003077777", "p@ss"}, []string{"bob"}},
+		{"aliased unit", "mysql_test(s1)", []string{"110101199003077777", "p@ss"}, []string{"bob"}},
+		{"not configured", "student(s1)", []string{"p@ss"}, []string{"110101199003077777", "bob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var maskFields []string
+			if dbCfg, _ := unitDBConfig(NewQuery(tt.unit)); dbCfg != nil {
+				maskFields = dbCfg.MaskFields
+			}
+
+			out := maskJSON(where, maskFields)
+			for _, v := range tt.masked {
+				if strings.Contains(out, v) {
+					t.Fatalf("%s is not masked in %s", v, out)
+				}
+			}
+
+			for _, v := range tt.plain {
+				if !strings.Contains(out, v) {
+					t.Fatalf("%s is masked in %s", v, out)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package horm
 import (
 	"reflect"
 
+	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/proto/sql"
 	"github.com/horm-database/common/types"
@@ -86,3 +87,40 @@ func (s *Query) realJoin(table string, joinType string, relation ...interface{})
 
 	return s
 }
+
+// Returning postgresql 新增、更新、删除时通过 RETURNING 返回指定列，例如自增 id、默认值、时间戳，
+// 返回的记录与查询结果一样解码到接收者，Insert 单条数据可用结构体接收，批量插入、Update、Delete 用切片接收。
+// 仅支持 postgresql，其他数据库返回错误，mysql 插入的自增 id 可以通过 proto.ModRet 的 ID 获取。
+func (s *Query) Returning(columns ...string) *Query {
+	typ, err := s.dbType()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	if typ != dbTypePostgreSQL {
+		s.Error = errs.Newf(errs.ErrReqParamInvalid, "returning is only supported by postgresql, got %s", typ)
+		return s
+	}
+
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+
+	return s.SetParam(paramReturning, columns)
+}
+
+// checkReturning RETURNING 只能用于新增、替换、更新、删除语句
+func checkReturning(q *Query) error {
+	if q.Unit.Params[paramReturning] == nil {
+		return nil
+	}
+
+	switch q.Unit.Op {
+	case consts.OpInsert, consts.OpReplace, consts.OpUpdate, consts.OpDelete:
+		return nil
+	}
+
+	return errs.Newf(errs.ErrReqParamInvalid, "unit %s returning is only valid for insert, replace, update and delete, got op %s",
+		q.Unit.Name, q.Unit.Op)
+}
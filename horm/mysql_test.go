@@ -0,0 +1,87 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+func TestReturning(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func() *Query
+		want  []string
+		code  int
+	}{
+		{"insert", func() *Query { return NewQuery("postgres_test").Insert(Map{"name": "a"}).Returning("id", "created_at") },
+			[]string{"id", "created_at"}, 0},
+		{"update all columns", func() *Query {
+			return NewQuery("postgres_test").Returning().Update(Map{"age": 1}, Where{"id": 1})
+		}, []string{"*"}, 0},
+		{"delete", func() *Query { return NewQuery("postgres_test(p1)").Delete(Where{"id": 1}).Returning("id") },
+			[]string{"id"}, 0},
+		{"find", func() *Query { return NewQuery("postgres_test").Find(Where{"id": 1}).Returning("id") },
+			nil, errs.ErrReqParamInvalid},
+		{"mysql", func() *Query { return NewQuery("mysql_test").Insert(Map{"name": "a"}).Returning("id") },
+			nil, errs.ErrReqParamInvalid},
+		{"clickhouse", func() *Query { return NewQuery("clickhouse_test").Insert(Map{"name": "a"}).Returning("id") },
+			nil, errs.ErrReqParamInvalid},
+		{"elastic", func() *Query { return NewQuery("es_test").Insert(Map{"name": "a"}).Returning("id") },
+			nil, errs.ErrReqParamInvalid},
+		{"unknown db type", func() *Query { return NewQuery("student").Insert(Map{"name": "a"}).Returning("id") },
+			nil, errs.ErrDBConfigNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query()
+			units, err := createUnits(q)
+			if errs.Code(err) != tt.code {
+				t.Fatalf("create units error %v, want code %d", err, tt.code)
+			}
+
+			if tt.code == 0 && !reflect.DeepEqual(units[0].Params[paramReturning], tt.want) {
+				t.Fatalf("returning %v, want %v", units[0].Params[paramReturning], tt.want)
+			}
+		})
+	}
+}
+
+func TestReturningDecode(t *testing.T) {
+	var calls int32
+	var unit *proto.Unit
+	c := NewClient("ws_test.app1.server1.service1",
+		WithInterceptor(mockInterceptor(&calls, unitHandler(&unit, `[{"id":7,"created_at":"2024-06-12 10:00:00"}]`))))
+
+	var rows []map[string]interface{}
+	_, err := NewQuery("postgres_test").Insert([]map[string]interface{}{{"name": "a"}}).
+		Returning("id", "created_at").WithClient(c).Exec(context.Background(), &rows)
+	if err != nil {
+		t.Fatalf("exec error: %v", err)
+	}
+
+	if len(rows) != 1 || rows[0]["created_at"] != "2024-06-12 10:00:00" {
+		t.Fatalf("returning rows %v", rows)
+	}
+
+	if !reflect.DeepEqual(unit.Params[paramReturning], []interface{}{"id", "created_at"}) {
+		t.Fatalf("request returning %v", unit.Params[paramReturning])
+	}
+}
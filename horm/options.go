@@ -15,35 +15,95 @@
 package horm
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/log/logger"
 	"github.com/horm-database/common/snowflake"
 	"github.com/horm-database/common/util"
 
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
 // Options are client options.
 type Options struct {
-	WorkspaceID int    // workspace id
-	Encryption  int8   // frame encryption
-	Token       string // workspace token
-	Timeout     uint32 // timeout Millisecond
-	Name        string // call name it better to be workspace_name.app.server.service
-	Caller      string // get from name
-	Appid       uint64 // appid
-	Secret      string // secret
-	Target      string // server target address
-	LocalIP     string // 本地 ip
-	Location    struct {
+	WorkspaceID       int    // workspace id
+	Encryption        int8   // frame encryption
+	Crypter           int8   // frame crypter algorithm, only used when encryption is encrypt
+	Token             string // workspace token
+	Timeout           uint32 // timeout Millisecond
+	Compress          uint32 // compress type of request body
+	CompressThreshold int    // request body is compressed only when it's size >= CompressThreshold
+	Name              string // call name it better to be workspace_name.app.server.service
+	Caller            string // get from name
+	Appid             uint64 // appid
+	Secret            string // secret
+	Target            string // server target address
+	LocalIP           string // 本地 ip
+	Location          struct {
 		Region string // 区域
 		Zone   string // 城市
 		Compus string // 园区
 	}
+	Retry    *RetryPolicy      // retry policy, nil means no retry
+	Metadata map[string]string // metadata of the call, only sent to server when MetadataHeader is set
+
+	// MetadataHeader encodes Metadata as a json object into the reserved Bak field of request header,
+	// it's a protocol extension, enable it only when the server parses Bak as metadata.
+	MetadataHeader bool
+
+	TracerProvider trace.TracerProvider // opentelemetry tracer provider, nil means global provider
+
+	DryRun bool // only build request header and body without sending
+
+	Interceptors []Interceptor // request interceptors
+
+	Strict bool // PExec returns *MultiError when any unit failed
+
+	Coalesce bool // collapse concurrent identical read-only requests into one round-trip
+
+	Cache *ResultCache // local result cache used by Query.Cache, nil means results are not cached
+}
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大请求次数（包含首次请求），小于等于 1 表示不重试
+	Backoff     time.Duration // 重试间隔
+	Codes       []int         // 可重试的错误码，为空时重试超时、网络错误、选址失败、服务端过载等可重试错误
+	Writes      bool          // 写操作是否重试超时、网络错误，这类请求可能已被服务端执行，重试可能重复写入，默认不重试
+}
+
+// retryable 第 attempt 次请求失败后是否需要重试，非只读请求默认仅重试选址失败、过载等未被执行的错误
+func (r *RetryPolicy) retryable(attempt int, readOnly bool, err error) bool {
+	if r == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+
+	if !readOnly && !r.Writes && maybeApplied(err) {
+		return false
+	}
+
+	if len(r.Codes) == 0 {
+		return IsRetryable(err)
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	for _, code := range r.Codes {
+		if e.Code == code {
+			return true
+		}
+	}
+
+	return false
 }
 
 var options = make(map[string]*Options)
@@ -60,6 +120,18 @@ func getOptions(name string) *Options {
 	return opts
 }
 
+func (o *Options) clone() *Options {
+	opts := *o
+	opts.Interceptors = append([]Interceptor(nil), o.Interceptors...)
+	if o.Metadata != nil {
+		opts.Metadata = make(map[string]string, len(o.Metadata))
+		for k, v := range o.Metadata {
+			opts.Metadata[k] = v
+		}
+	}
+	return &opts
+}
+
 // Option sets client options.
 type Option func(*Options)
 
@@ -70,6 +142,16 @@ func WithTimeout(timeout uint32) Option {
 	}
 }
 
+// WithCompress returns an Option that sets compress type of request body,
+// the body will be compressed automatically when it's size is not less than threshold.
+// Only CompressTypeGzip is defined by the protocol, zstd, snappy and lz4 require server support.
+func WithCompress(compressType uint32, threshold int) Option {
+	return func(o *Options) {
+		o.Compress = compressType
+		o.CompressThreshold = threshold
+	}
+}
+
 // WithName returns an Option that sets call name of client.
 func WithName(name string) Option {
 	return func(o *Options) {
@@ -77,6 +159,13 @@ func WithName(name string) Option {
 	}
 }
 
+// WithCaller returns an Option that sets caller of request header.
+func WithCaller(caller string) Option {
+	return func(o *Options) {
+		o.Caller = caller
+	}
+}
+
 // WithAppID returns an Option that sets appid.
 func WithAppID(appid uint64) Option {
 	return func(o *Options) {
@@ -119,6 +208,13 @@ func WithEncryption(encryption int8) Option {
 	}
 }
 
+// WithCrypter returns an Option that sets crypter algorithm of encrypt frame.
+func WithCrypter(crypter int8) Option {
+	return func(o *Options) {
+		o.Crypter = crypter
+	}
+}
+
 // WithToken returns an Option that sets token of workspace of server.
 func WithToken(token string) Option {
 	return func(o *Options) {
@@ -126,6 +222,100 @@ func WithToken(token string) Option {
 	}
 }
 
+// WithRetry returns an Option that sets retry policy.
+// param: maxAttempts 最大请求次数（包含首次请求）
+// param: backoff 重试间隔
+// param: codes 可重试的错误码，不传时重试超时、网络错误、选址失败、服务端过载等可重试错误，见 IsRetryable，
+// 写操作默认不重试超时、网络错误，见 WithRetryWrites
+func WithRetry(maxAttempts int, backoff time.Duration, codes ...int) Option {
+	return func(o *Options) {
+		o.Retry = &RetryPolicy{
+			MaxAttempts: maxAttempts,
+			Backoff:     backoff,
+			Codes:       codes,
+		}
+	}
+}
+
+// WithRetryWrites returns an Option that allows retrying timeouts and network errors of write requests,
+// which may have been executed by server already. It must be set after WithRetry.
+func WithRetryWrites() Option {
+	return func(o *Options) {
+		if o.Retry != nil {
+			retry := *o.Retry
+			retry.Writes = true
+			o.Retry = &retry
+		}
+	}
+}
+
+// WithMetadata returns an Option that sets metadata of the call,
+// it is passed through to server only when WithMetadataHeader is set.
+func WithMetadata(key, value string) Option {
+	return func(o *Options) {
+		if o.Metadata == nil {
+			o.Metadata = map[string]string{}
+		}
+		o.Metadata[key] = value
+	}
+}
+
+// WithMetadataHeader returns an Option that sends metadata (including W3C trace-context) to server
+// as a json object in the reserved Bak field of request header, the server must support this contract.
+func WithMetadataHeader() Option {
+	return func(o *Options) {
+		o.MetadataHeader = true
+	}
+}
+
+// WithTracerProvider returns an Option that sets opentelemetry tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *Options) {
+		o.TracerProvider = tp
+	}
+}
+
+// WithDryRun returns an Option that builds request header and body without sending,
+// exec returns ErrDryRun, header and body can be viewed in Query.RequestHeader and Query.RequestBody.
+func WithDryRun() Option {
+	return func(o *Options) {
+		o.DryRun = true
+	}
+}
+
+// WithStrict returns an Option that makes PExec return *MultiError when any unit failed,
+// by default unit errors are only written into the receivers of WithReceiver and WithPExecResult.
+func WithStrict() Option {
+	return func(o *Options) {
+		o.Strict = true
+	}
+}
+
+// WithCoalesce returns an Option that collapses concurrent identical read-only requests
+// (same client, workspace, target, query mode and marshalled units) into one round-trip,
+// every caller decodes the shared result into its own receivers and never waits beyond its own deadline.
+// mutating ops, raw queries and bytes are never coalesced.
+func WithCoalesce() Option {
+	return func(o *Options) {
+		o.Coalesce = true
+	}
+}
+
+// WithResultCache returns an Option that sets the local result cache used by Query.Cache,
+// Query.Cache has no effect without it, clients sharing a cache also share its automatic invalidation.
+func WithResultCache(c *ResultCache) Option {
+	return func(o *Options) {
+		o.Cache = c
+	}
+}
+
+// WithInterceptor returns an Option that appends request interceptors, the first added runs first.
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(o *Options) {
+		o.Interceptors = append(o.Interceptors, interceptors...)
+	}
+}
+
 // WithLocation returns an Option that sets location of client.
 func WithLocation(region, zone, compus string) Option {
 	return func(o *Options) {
@@ -161,12 +351,15 @@ type config struct {
 }
 
 type serverConfig struct {
-	WorkspaceID int             `yaml:"workspace_id"` // workspace
-	Encryption  int8            `yaml:"encryption"`   // 帧签名方式 0-无（默认） 1-签名 2-加密
-	Token       string          `yaml:"token"`        // token
-	Target      string          `yaml:"target"`       // workspace 地址
-	Timeout     uint32          `yaml:"timeout"`      // 接口调用超时时间（毫秒）
-	Caller      []*callerConfig `yaml:"caller"`       // 调用方信息
+	WorkspaceID       int             `yaml:"workspace_id"`       // workspace
+	Encryption        int8            `yaml:"encryption"`         // 帧签名方式 0-无（默认） 1-签名 2-加密
+	Crypter           int8            `yaml:"crypter"`            // 加密算法 0-默认 1-AES-256-GCM 2-ChaCha20-Poly1305，仅加密帧有效
+	Token             string          `yaml:"token"`              // token
+	Target            string          `yaml:"target"`             // workspace 地址
+	Timeout           uint32          `yaml:"timeout"`            // 接口调用超时时间（毫秒）
+	Compress          uint32          `yaml:"compress"`           // 请求压缩算法 0-不压缩（默认） 1-gzip 2-zstd 3-snappy 4-lz4（2~4 需服务端支持）
+	CompressThreshold int             `yaml:"compress_threshold"` // 请求体大于等于该值（字节）时才压缩
+	Caller            []*callerConfig `yaml:"caller"`             // 调用方信息
 }
 
 type callerConfig struct {
@@ -188,6 +381,8 @@ type dbConfig struct {
 	WarnTimeout  int    `yaml:"warn_timeout"`  // 告警超时（ms），如果请求耗时超过这个时间，就会打 warning 日志
 	OmitError    int8   `yaml:"omit_error"`    // 是否忽略 error 日志，0-否 1-是
 	Debug        int8   `yaml:"debug"`         // 是否开启 debug 日志，正常的数据库请求也会被打印到日志，0-否 1-是，会造成海量日志，慎重开启
+
+	MaskFields []string `yaml:"mask_fields"` // 日志脱敏字段，另外字段名包含 password、passwd、pwd、secret、token 的字段默认脱敏
 }
 
 var dbConfigs = make(map[string]*dbConfig)
@@ -219,6 +414,10 @@ func LoadConfig(confPath ...string) {
 		snowflake.SetMachineID(cfg.MachineID)
 	}
 
+	if len(cfg.Log) > 0 {
+		logger.CreateDefaultLogger(cfg.Log)
+	}
+
 	for _, v := range cfg.DB {
 		dbConfigs[v.Name] = v
 	}
@@ -226,16 +425,19 @@ func LoadConfig(confPath ...string) {
 	for _, server := range cfg.Server {
 		for _, caller := range server.Caller {
 			opts := Options{
-				WorkspaceID: server.WorkspaceID,
-				Token:       server.Token,
-				Encryption:  server.Encryption,
-				Timeout:     caller.Timeout,
-				Name:        caller.Name,
-				Caller:      caller.Name,
-				Appid:       caller.AppID,
-				Secret:      caller.Secret,
-				Target:      server.Target,
-				LocalIP:     cfg.LocalIP,
+				WorkspaceID:       server.WorkspaceID,
+				Token:             server.Token,
+				Encryption:        server.Encryption,
+				Crypter:           server.Crypter,
+				Timeout:           caller.Timeout,
+				Compress:          server.Compress,
+				CompressThreshold: server.CompressThreshold,
+				Name:              caller.Name,
+				Caller:            caller.Name,
+				Appid:             caller.AppID,
+				Secret:            caller.Secret,
+				Target:            server.Target,
+				LocalIP:           cfg.LocalIP,
 			}
 
 			i := strings.Index(caller.Name, ".")
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+)
+
+func TestRetryWrites(t *testing.T) {
+	find := func() *Query { return NewQuery("student").Find(Where{"id": 1}) }
+	update := func() *Query { return NewQuery("student").Update(Map{"age": 1}, Where{"id": 1}) }
+
+	tests := []struct {
+		name  string
+		q     func() *Query
+		code  int
+		opts  []Option
+		calls int32
+	}{
+		{"read timeout", find, errs.ErrClientTimeout, []Option{WithRetry(3, 0)}, 3},
+		{"write timeout", update, errs.ErrClientTimeout, []Option{WithRetry(3, 0)}, 1},
+		{"write network", update, errs.ErrClientNet, []Option{WithRetry(3, 0)}, 1},
+		{"write route", update, errs.ErrClientRoute, []Option{WithRetry(3, 0)}, 3},
+		{"write timeout by code", update, errs.ErrClientTimeout,
+			[]Option{WithRetry(3, 0, errs.ErrClientTimeout)}, 1},
+		{"write timeout opt in", update, errs.ErrClientTimeout, []Option{WithRetry(3, 0), WithRetryWrites()}, 3},
+		{"opt in without retry", update, errs.ErrClientTimeout, []Option{WithRetryWrites()}, 1},
+		{"locked read timeout", func() *Query {
+			return NewTransaction("mysql_test", NewQuery("mysql_test").Find(Where{"id": 1}).ForUpdate())
+		}, errs.ErrClientTimeout, []Option{WithRetry(3, 0)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			handler := func(head *proto.RequestHeader, body []byte) (*proto.ResponseHeader, []byte, error) {
+				return nil, nil, errs.New(tt.code, "request failed")
+			}
+
+			opts := append([]Option{WithInterceptor(mockInterceptor(&calls, handler))}, tt.opts...)
+			c := NewClient("ws_test.app1.server1.service1", opts...)
+
+			if _, err := c.Exec(context.Background(), tt.q()); err == nil {
+				t.Fatalf("want error")
+			}
+
+			if got := atomic.LoadInt32(&calls); got != tt.calls {
+				t.Fatalf("requests %d, want %d", got, tt.calls)
+			}
+		})
+	}
+}
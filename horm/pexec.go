@@ -0,0 +1,147 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UnitResult 并行查询单个执行单元的执行结果
+type UnitResult struct {
+	Key          string // 执行单元 key（别名或名称）
+	Name         string // 执行单元名
+	Err          error  // 执行单元错误，包括服务端返回错误与解码错误
+	IsNil        bool   // 是否为空
+	DecodeFailed bool   // 结果解码失败
+}
+
+// PExecResult 并行查询结果，按执行单元顺序记录每个执行单元的 key、错误、是否为空、是否解码失败，
+// 未调用 WithReceiver 的执行单元错误也会被记录。
+type PExecResult struct {
+	Units []*UnitResult
+}
+
+// AllSucceeded 所有执行单元是否全部成功
+func (r *PExecResult) AllSucceeded() bool {
+	return r.FirstError() == nil
+}
+
+// FirstError 第一个失败执行单元的错误，全部成功返回 nil
+func (r *PExecResult) FirstError() error {
+	if r == nil {
+		return nil
+	}
+
+	for _, unit := range r.Units {
+		if unit.Err != nil {
+			return unit.Err
+		}
+	}
+
+	return nil
+}
+
+// Failed 所有失败的执行单元
+func (r *PExecResult) Failed() []*UnitResult {
+	if r == nil {
+		return nil
+	}
+
+	var failed []*UnitResult
+	for _, unit := range r.Units {
+		if unit.Err != nil {
+			failed = append(failed, unit)
+		}
+	}
+
+	return failed
+}
+
+// Get 根据 key 获取执行单元结果，不存在返回 nil
+func (r *PExecResult) Get(key string) *UnitResult {
+	if r == nil {
+		return nil
+	}
+
+	for _, unit := range r.Units {
+		if unit.Key == key {
+			return unit
+		}
+	}
+
+	return nil
+}
+
+// Range 按顺序遍历执行单元结果，fn 返回 false 时停止遍历
+func (r *PExecResult) Range(fn func(unit *UnitResult) bool) {
+	if r == nil {
+		return
+	}
+
+	for _, unit := range r.Units {
+		if !fn(unit) {
+			return
+		}
+	}
+}
+
+// Err 存在失败执行单元时返回 *MultiError，否则返回 nil
+func (r *PExecResult) Err() error {
+	if r.AllSucceeded() {
+		return nil
+	}
+	return &MultiError{r}
+}
+
+// MultiError 并行查询部分或全部执行单元失败，strict 模式下由 PExec 返回，
+// errors.Is、errors.As 会匹配任一失败执行单元的错误
+type MultiError struct {
+	*PExecResult
+}
+
+// Error implements error.
+func (e *MultiError) Error() string {
+	failed := e.Failed()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("horm: %d of %d units failed", len(failed), len(e.Units)))
+	for _, unit := range failed {
+		sb.WriteString(fmt.Sprintf("; %s: %v", unit.Key, unit.Err))
+	}
+
+	return sb.String()
+}
+
+// Is 任一失败执行单元错误匹配 target
+func (e *MultiError) Is(target error) bool {
+	for _, unit := range e.Failed() {
+		if errors.Is(unit.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As 将第一个匹配的失败执行单元错误赋值给 target
+func (e *MultiError) As(target interface{}) bool {
+	for _, unit := range e.Failed() {
+		if errors.As(unit.Err, target) {
+			return true
+		}
+	}
+	return false
+}
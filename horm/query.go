@@ -16,34 +16,47 @@ package horm
 
 import (
 	"context"
+	"time"
 
 	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/types"
 	"github.com/horm-database/go-horm/horm/codec"
 )
 
 // Query 请求语句
 type Query struct {
-	Unit        *proto.Unit    // 请求单元
-	first       *Query         // 首查询
-	last        *Query         // 并行查询上一个查询
-	next        *Query         // 并行查询下一个查询
-	sub         *Query         // 子查询
-	parent      *Query         // 父查询
-	trans       *Query         // 事务语句
-	Client      Client         // 客户端
-	Error       error          // Query 语句错误
-	Key         string         // 语句 key
-	Receiver    []interface{}  // 结果接收
-	IsNil       *bool          // 是否包含空值
-	RespError   *error         // 返回错误
-	Compress    bool           // 是否压缩 false - 不压缩 true - 压缩
-	ResultType  consts.RetType // 返回数据类型
-	Coder       codec.Codec    // 数据编解码器
-	RequestID   uint64         // 请求 id
-	TraceID     string         // 请求 trace_id
-	RequestBody []byte         // 请求体
+	Unit          *proto.Unit          // 请求单元
+	first         *Query               // 首查询
+	last          *Query               // 并行查询上一个查询
+	next          *Query               // 并行查询下一个查询
+	sub           *Query               // 子查询
+	parent        *Query               // 父查询
+	trans         *Query               // 事务语句
+	Client        Client               // 客户端
+	Error         error                // Query 语句错误
+	Key           string               // 语句 key
+	Receiver      []interface{}        // 结果接收
+	IsNil         *bool                // 是否包含空值
+	RespError     *error               // 返回错误
+	Compress      bool                 // 是否压缩 false - 不压缩 true - 压缩
+	CompressType  uint32               // 客户端压缩请求体的算法，为 0 时不压缩请求体
+	ResultType    consts.RetType       // 返回数据类型
+	Coder         codec.Codec          // 数据编解码器
+	RequestID     uint64               // 请求 id
+	TraceID       string               // 请求 trace_id
+	CallOptions   []Option             // 本次查询调用参数，会覆盖客户端参数
+	Timeout       time.Duration        // 执行单元超时时间，为 0 时不限制（受请求超时时间约束）
+	RespInfo      *ResponseInfo        // 请求返回信息接收
+	PResult       *PExecResult         // 并行查询结果接收
+	CacheTTL      time.Duration        // 本地结果缓存时间，为 0 时不缓存
+	CacheTags     []string             // 本地结果缓存标签
+	RequestBody   []byte               // 请求体
+	RequestHeader *proto.RequestHeader // 请求头，DryRun 模式下可查看实际构造的请求头
+	addr          string               // 请求节点地址
+	keysetDir     int                  // 游标分页方向
+	keysetCursor  string               // 游标分页游标
 }
 
 // Reset 语句初始化
@@ -62,15 +75,104 @@ func (s *Query) Reset() *Query {
 	s.IsNil = nil
 	s.RespError = nil
 	s.Compress = false
+	s.CompressType = 0
 	s.ResultType = 0
 	s.Coder = nil
 	s.RequestID = 0
 	s.TraceID = ""
+	s.CallOptions = nil
+	s.Timeout = 0
+	s.RespInfo = nil
+	s.PResult = nil
+	s.CacheTTL = 0
+	s.CacheTags = nil
 	s.RequestBody = []byte{}
+	s.RequestHeader = nil
+	s.addr = ""
+	s.keysetDir = 0
+	s.keysetCursor = ""
 
 	return s
 }
 
+// clone 复制单个执行语句，执行单元的条件、参数、扩展信息、数据等复制为新的 map、切片，修改副本不影响原语句，
+// 副本不包含并行查询、子查询、事务语句与结果接收，客户端取自首查询。
+func (s *Query) clone() *Query {
+	q := *s
+	unit := *s.Unit
+
+	unit.Column = append([]string(nil), s.Unit.Column...)
+	unit.Order = append([]string(nil), s.Unit.Order...)
+	unit.Where = copyMap(s.Unit.Where)
+	unit.Params = copyMap(s.Unit.Params)
+	unit.Extend = copyMap(s.Unit.Extend)
+	unit.Having = copyMap(s.Unit.Having)
+	unit.Data = copyMap(s.Unit.Data)
+	if s.Unit.Datas != nil {
+		unit.Datas = make([]map[string]interface{}, len(s.Unit.Datas))
+		for i, data := range s.Unit.Datas {
+			unit.Datas[i] = copyMap(data)
+		}
+	}
+	if s.Unit.DataType != nil {
+		unit.DataType = make(map[string]types.Type, len(s.Unit.DataType))
+		for k, v := range s.Unit.DataType {
+			unit.DataType[k] = v
+		}
+	}
+	unit.Group = append([]string(nil), s.Unit.Group...)
+
+	q.Unit = &unit
+	q.first = &q
+	q.last, q.next, q.sub, q.parent, q.trans = nil, nil, nil, nil, nil
+	q.Client = s.GetHead().Client
+	q.Receiver, q.IsNil, q.RespError, q.PResult = nil, nil, nil, nil
+	q.RequestBody, q.RequestHeader = []byte{}, nil
+	q.keysetDir, q.keysetCursor = 0, ""
+
+	return &q
+}
+
+// cloneTree 复制整个查询语句（包括并行查询、子查询、事务），修改副本不影响原语句
+func (s *Query) cloneTree() *Query {
+	return s.GetHead().cloneTreeOf(nil)
+}
+
+// cloneTreeOf 复制 s 及其之后的并行查询、子查询、事务语句，parent 为副本的父查询
+func (s *Query) cloneTreeOf(parent *Query) *Query {
+	if s == nil {
+		return nil
+	}
+
+	var head, last *Query
+	for p := s; p != nil; p = p.next {
+		c := p.clone()
+		c.parent = parent
+		c.sub = p.sub.cloneTreeOf(c)
+		c.trans = p.trans.cloneTreeOf(nil)
+
+		if head == nil {
+			head = c
+		} else {
+			c.first, c.last, last.next = head, last, c
+		}
+		last = c
+	}
+	return head
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	ret := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
+
 // NewQuery 创建新执行语句
 // param: name 为执行语句名称
 func NewQuery(name string) *Query {
@@ -223,6 +325,13 @@ func (s *Query) SetCompress() *Query {
 	return s
 }
 
+// SetCompressType 指定算法，由客户端压缩请求体，并解压服务端按同一算法压缩的返回，
+// 可选 client.CompressTypeGzip，以及需服务端支持的 client.CompressTypeZstd、client.CompressTypeSnappy、client.CompressTypeLZ4
+func (s *Query) SetCompressType(compressType uint32) *Query {
+	s.CompressType = compressType
+	return s
+}
+
 // WithRequestID 设置 request_id
 func (s *Query) WithRequestID(id uint64) *Query {
 	s.RequestID = id
@@ -234,3 +343,37 @@ func (s *Query) WithTraceID(id string) *Query {
 	s.TraceID = id
 	return s
 }
+
+// WithCallOptions 设置本次查询的调用参数，例如超时时间、目标地址、重试策略、调用方、metadata 等，会覆盖客户端参数
+func (s *Query) WithCallOptions(opts ...Option) *Query {
+	s.CallOptions = append(s.CallOptions, opts...)
+	return s
+}
+
+// SetTimeout 设置执行单元超时时间，会随请求传递到服务端，用于并行查询、复合查询中单独控制每个执行单元的超时，
+// 单执行单元查询时，请求超时时间取其与客户端超时时间的较小值。
+func (s *Query) SetTimeout(timeout time.Duration) *Query {
+	s.Timeout = timeout
+	return s
+}
+
+// WithResponseInfo 接收请求返回信息，包括请求次数、超时时间以及选址、建连、写、服务端、读、解码等各阶段耗时
+func (s *Query) WithResponseInfo(info *ResponseInfo) *Query {
+	s.RespInfo = info
+	return s
+}
+
+// WithPExecResult 接收并行查询结果，包括每个执行单元的 key、错误、是否为空、是否解码失败
+func (s *Query) WithPExecResult(result *PExecResult) *Query {
+	s.PResult = result
+	return s
+}
+
+// Cache 缓存查询结果到客户端本地缓存，需要通过 WithResultCache 为客户端设置缓存，否则不生效。仅对只读操作生效，
+// 空结果同样会被缓存，缓存以执行单元的表名和 tags 打标，同一客户端对该表的新增、修改、删除操作（包括直接输入的语句）
+// 会自动失效缓存，也可以通过 ResultCache.Invalidate 按标签手动失效。
+func (s *Query) Cache(ttl time.Duration, tags ...string) *Query {
+	s.CacheTTL = ttl
+	s.CacheTags = tags
+	return s
+}
@@ -173,7 +173,7 @@ func (s *Query) NotMatch(key string, value interface{}) *Query {
 	return s
 }
 
-// UpdateKV 更新字段，快速更新键值对 key = value
+// UpdateKV 更新字段，快速更新键值对 key = value，value 可以是 Incr、Expr、Now 等更新表达式
 func (s *Query) UpdateKV(key string, value interface{}, kvs ...interface{}) *Query {
 	s.Op("update")
 
@@ -217,5 +217,5 @@ func (s *Query) UpdateKV(key string, value interface{}, kvs ...interface{}) *Que
 		}
 	}
 
-	return s
+	return s.encodeExprs()
 }
@@ -0,0 +1,199 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replay 请求录制与回放，录制的请求与返回可在 CI 中回放，无需依赖 access 服务。
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/horm-database/common/codec"
+	"github.com/horm-database/common/json"
+	cp "github.com/horm-database/common/proto"
+	hcodec "github.com/horm-database/go-horm/horm/codec"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Format 录制文件格式
+type Format int8
+
+const (
+	FormatJSONL  Format = 1 // 每行一个 json 记录，便于阅读与修改
+	FormatBinary Format = 2 // 每个记录为请求帧 + 返回帧，与网络传输的帧格式一致
+)
+
+// Record 一次请求与返回的录制记录
+type Record struct {
+	Request       *cp.RequestHeader   `json:"request"`                  // 请求头，仅保留 query_mode、request_id、trace_id、caller 等
+	RequestBody   jsoniter.RawMessage `json:"request_body"`             // 请求体，即执行单元 json
+	Response      *cp.ResponseHeader  `json:"response"`                 // 返回头，请求失败时 err 为错误信息
+	ResponseBody  jsoniter.RawMessage `json:"response_body,omitempty"`  // 返回体
+	ResponseBytes []byte              `json:"response_bytes,omitempty"` // 返回体不是 json 时以 base64 存储
+}
+
+// responseBody 获取返回体
+func (r *Record) responseBody() []byte {
+	if len(r.ResponseBody) > 0 {
+		return r.ResponseBody
+	}
+	return r.ResponseBytes
+}
+
+// writeRecord 写入一条记录
+func writeRecord(w io.Writer, format Format, r *Record) error {
+	switch format {
+	case FormatJSONL:
+		buf, err := json.Api.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(buf, '\n'))
+		return err
+	case FormatBinary:
+		reqFrame, err := constructFrame(r.Request, r.RequestBody)
+		if err != nil {
+			return err
+		}
+
+		respFrame, err := constructFrame(r.Response, r.responseBody())
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(append(reqFrame, respFrame...))
+		return err
+	default:
+		return fmt.Errorf("replay: unknown format %d", format)
+	}
+}
+
+// readRecords 读取所有记录
+func readRecords(rd io.Reader, format Format) ([]*Record, error) {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(rd)
+	case FormatBinary:
+		return readBinary(rd)
+	default:
+		return nil, fmt.Errorf("replay: unknown format %d", format)
+	}
+}
+
+func readJSONL(rd io.Reader) ([]*Record, error) {
+	var records []*Record
+
+	reader := bufio.NewReader(rd)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("replay: read jsonl record %d error: %v", len(records)+1, err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 { // 忽略空行
+			r := Record{}
+			if e := json.Api.Unmarshal(line, &r); e != nil {
+				return nil, fmt.Errorf("replay: decode jsonl record %d error: %v", len(records)+1, e)
+			}
+
+			if r.Request == nil || r.Response == nil {
+				return nil, fmt.Errorf("replay: jsonl record %d miss request or response", len(records)+1)
+			}
+
+			records = append(records, &r)
+		}
+
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+func readBinary(rd io.Reader) ([]*Record, error) {
+	var records []*Record
+
+	framer := hcodec.NewFramer(hcodec.NewReader(rd))
+	for {
+		reqFrame, err := framer.ReadFrame()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("replay: read request frame of record %d error: %v", len(records)+1, err)
+		}
+
+		respFrame, err := framer.ReadFrame()
+		if err != nil {
+			return nil, fmt.Errorf("replay: read response frame of record %d error: %v", len(records)+1, err)
+		}
+
+		r := Record{Request: &cp.RequestHeader{}, Response: &cp.ResponseHeader{}}
+
+		if r.RequestBody, err = extractFrame(reqFrame, r.Request); err != nil {
+			return nil, fmt.Errorf("replay: extract request frame of record %d error: %v", len(records)+1, err)
+		}
+
+		respBody, err := extractFrame(respFrame, r.Response)
+		if err != nil {
+			return nil, fmt.Errorf("replay: extract response frame of record %d error: %v", len(records)+1, err)
+		}
+
+		setResponseBody(&r, respBody)
+		records = append(records, &r)
+	}
+}
+
+// constructFrame 将协议头与包体构造为普通帧
+func constructFrame(header proto.Message, body []byte) ([]byte, error) {
+	headerBuf, err := proto.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.NewFrameHead().Construct(headerBuf, body)
+}
+
+// extractFrame 从普通帧中解析协议头，返回包体
+func extractFrame(frame []byte, header proto.Message) ([]byte, error) {
+	frameHead := codec.FrameHead{}
+	frameHead.Extract(frame)
+
+	end := codec.FrameHeadLen + int(frameHead.HeaderLen)
+	if frameHead.HeaderLen == 0 || end > len(frame) {
+		return nil, errors.New("frame head len invalid")
+	}
+
+	if err := proto.Unmarshal(frame[codec.FrameHeadLen:end], header); err != nil {
+		return nil, err
+	}
+
+	return frame[end:], nil
+}
+
+func setResponseBody(r *Record, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	if json.Api.Valid(body) {
+		r.ResponseBody = body
+	} else {
+		r.ResponseBytes = body
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/log"
+	cp "github.com/horm-database/common/proto"
+	"github.com/horm-database/go-horm/horm"
+)
+
+// Recorder 请求录制器，作为拦截器录制每一次请求与返回，例如：
+// horm.NewClient(name, horm.WithInterceptor(replay.NewRecorder(file, replay.FormatJSONL).Intercept))
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+}
+
+// NewRecorder 创建请求录制器
+// param: w 录制写入，需要调用方自行关闭
+// param: format 录制文件格式
+func NewRecorder(w io.Writer, format Format) *Recorder {
+	return &Recorder{
+		w:      w,
+		format: format,
+	}
+}
+
+var _ horm.Interceptor = (&Recorder{}).Intercept
+
+// Intercept 拦截请求并录制，网络、超时等客户端错误不录制，录制失败不影响请求结果。
+func (r *Recorder) Intercept(ctx context.Context, head *cp.RequestHeader,
+	reqBody []byte, next horm.Invoker) (*cp.ResponseHeader, []byte, error) {
+	respHeader, respBody, err := next(ctx, head, reqBody)
+
+	record, ok := newRecord(head, reqBody, respHeader, respBody, err)
+	if !ok {
+		return respHeader, respBody, err
+	}
+
+	r.mu.Lock()
+	writeErr := writeRecord(r.w, r.format, record)
+	r.mu.Unlock()
+
+	if writeErr != nil {
+		log.Errorf(ctx, errs.ErrClientEncode, "replay: record request_id %d error: %v", head.RequestId, writeErr)
+	}
+
+	return respHeader, respBody, err
+}
+
+func newRecord(head *cp.RequestHeader, reqBody []byte,
+	respHeader *cp.ResponseHeader, respBody []byte, err error) (*Record, bool) {
+	if err != nil {
+		e, ok := err.(*errs.Error)
+		if !ok || isClientError(e.Code) {
+			return nil, false
+		}
+
+		respHeader = &cp.ResponseHeader{
+			Version:   head.Version,
+			QueryMode: head.QueryMode,
+			RequestId: head.RequestId,
+			Err:       &cp.Error{Type: int32(e.Type), Code: int32(e.Code), Msg: e.Msg},
+		}
+		respBody = nil
+	}
+
+	if respHeader == nil {
+		return nil, false
+	}
+
+	r := Record{
+		Request: &cp.RequestHeader{
+			Version:   head.Version,
+			QueryMode: head.QueryMode,
+			RequestId: head.RequestId,
+			TraceId:   head.TraceId,
+			Caller:    head.Caller,
+			Appid:     head.Appid,
+		},
+		RequestBody: reqBody,
+		Response:    respHeader,
+	}
+
+	setResponseBody(&r, respBody)
+
+	return &r, true
+}
+
+// isClientError 客户端产生的错误，并非服务端返回
+func isClientError(code int) bool {
+	switch code {
+	case errs.ErrClientNotInit, errs.ErrClientDecode, errs.ErrClientEncode, errs.ErrClientRoute,
+		errs.ErrClientConnect, errs.ErrClientTimeout, errs.ErrClientNet, errs.ErrClientCanceled,
+		errs.ErrClientReadFrame, errs.ErrRequestIDNotMatch, errs.ErrQueryModeNotMatch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	cp "github.com/horm-database/common/proto"
+)
+
+// serve 模拟服务端，返回请求对应的结果
+func serve(resps map[string]string) func(ctx context.Context, head *cp.RequestHeader,
+	reqBody []byte) (*cp.ResponseHeader, []byte, error) {
+	return func(ctx context.Context, head *cp.RequestHeader, reqBody []byte) (*cp.ResponseHeader, []byte, error) {
+		resp, ok := resps[string(reqBody)]
+		if !ok {
+			return nil, nil, errs.Newf(errs.ErrClientTimeout, "timeout")
+		}
+
+		if strings.HasPrefix(resp, "error:") {
+			return nil, nil, &errs.Error{Type: errs.ETypeSystem, Code: 1062, Msg: strings.TrimPrefix(resp, "error:")}
+		}
+
+		return &cp.ResponseHeader{QueryMode: head.QueryMode, RequestId: head.RequestId}, []byte(resp), nil
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	const (
+		find   = `[{"name":"student","op":"find","where":{"id":1}}]`
+		insert = `[{"name":"student","op":"insert","data":{"id":1}}]`
+		miss   = `[{"name":"student","op":"find","where":{"id":2}}]`
+	)
+
+	resps := map[string]string{
+		find:   `{"id":1,"name":"jerry"}`,
+		insert: "error:duplicate entry",
+	}
+
+	for _, format := range []Format{FormatJSONL, FormatBinary} {
+		buf := bytes.Buffer{}
+		recorder := NewRecorder(&buf, format)
+
+		for i, body := range []string{find, insert, miss} {
+			head := &cp.RequestHeader{QueryMode: 1, RequestId: uint64(i + 1), Caller: "test"}
+			_, _, _ = recorder.Intercept(context.Background(), head, []byte(body), serve(resps))
+		}
+
+		if format == FormatJSONL {
+			if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+				t.Fatalf("jsonl records %d, want 2 (client errors not recorded): %s", len(lines), buf.String())
+			}
+		}
+
+		p, err := Load(&buf, format)
+		if err != nil {
+			t.Fatalf("format %d load error %v", format, err)
+		}
+
+		// 回放请求头的 request_id 与录制时不同
+		head := &cp.RequestHeader{QueryMode: 1, RequestId: 100}
+		respHeader, respBody, err := p.Intercept(context.Background(), head, []byte(find), nil)
+		if err != nil || string(respBody) != resps[find] || respHeader.RequestId != 100 {
+			t.Fatalf("format %d replay find: header %v body %s error %v", format, respHeader, respBody, err)
+		}
+
+		_, _, err = p.Intercept(context.Background(), head, []byte(insert), nil)
+		if e, ok := err.(*errs.Error); !ok || e.Code != 1062 || e.Msg != "duplicate entry" {
+			t.Fatalf("format %d replay insert error %v", format, err)
+		}
+
+		_, _, err = p.Intercept(context.Background(), head, []byte(miss), nil)
+		if errs.Code(err) != errs.ErrClientRoute {
+			t.Fatalf("format %d replay miss error %v", format, err)
+		}
+	}
+}
+
+func TestReplayMatch(t *testing.T) {
+	record := func(queryMode uint32, body, resp string) string {
+		return fmt.Sprintf(`{"request":{"query_mode":%d},"request_body":%s,"response":{},"response_body":%s}`+"\n",
+			queryMode, body, resp)
+	}
+
+	jsonl := record(1, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":1}`) +
+		record(1, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":2}`) +
+		record(2, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":3}`)
+
+	p, err := Load(strings.NewReader(jsonl), FormatJSONL)
+	if err != nil {
+		t.Fatalf("load error %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		queryMode uint32
+		body      string
+		want      string
+	}{
+		{"first", 1, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":1}`},
+		{"key order", 1, `[{"where":{"age":2,"id":1},"op":"find","name":"student"}]`, `{"n":2}`},
+		{"repeat last", 1, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":2}`},
+		{"query mode", 2, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, `{"n":3}`},
+		{"miss query mode", 3, `[{"name":"student","op":"find","where":{"id":1,"age":2}}]`, ""},
+		{"miss body", 1, `[{"name":"student","op":"find","where":{"id":1}}]`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head := &cp.RequestHeader{QueryMode: tt.queryMode, RequestId: 1}
+			_, respBody, err := p.Intercept(context.Background(), head, []byte(tt.body), nil)
+			if tt.want == "" {
+				if errs.Code(err) != errs.ErrClientRoute {
+					t.Fatalf("want miss, got %s error %v", respBody, err)
+				}
+				return
+			}
+
+			if err != nil || string(respBody) != tt.want {
+				t.Fatalf("got %s error %v, want %s", respBody, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		jsonl string
+	}{
+		{"bad json", `{"request":`},
+		{"miss response", `{"request":{"query_mode":1},"request_body":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(tt.jsonl), FormatJSONL); err == nil {
+				t.Fatalf("want load error")
+			}
+		})
+	}
+}
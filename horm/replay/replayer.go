@@ -0,0 +1,133 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	cp "github.com/horm-database/common/proto"
+	"github.com/horm-database/go-horm/horm"
+)
+
+// Replayer 请求回放器，按执行单元内容（查询模式 + 请求体）匹配录制的返回，
+// 相同请求录制了多次时按录制顺序依次返回，最后一次返回会被重复使用。
+type Replayer struct {
+	mu      sync.Mutex
+	records map[string][]*Record
+	cursors map[string]int
+}
+
+// Load 从录制内容加载回放器
+func Load(rd io.Reader, format Format) (*Replayer, error) {
+	records, err := readRecords(rd, format)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Replayer{
+		records: make(map[string][]*Record),
+		cursors: make(map[string]int),
+	}
+
+	for _, r := range records {
+		key := matchKey(r.Request.QueryMode, r.RequestBody)
+		p.records[key] = append(p.records[key], r)
+	}
+
+	return p, nil
+}
+
+// LoadFile 从录制文件加载回放器
+func LoadFile(fileName string, format Format) (*Replayer, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f, format)
+}
+
+// NewClient 创建回放客户端，所有请求从录制记录返回，不会发送到服务端。
+// param: name 配置名
+// param: p 回放器
+// param: opts 参数配置
+func NewClient(name string, p *Replayer, opts ...horm.Option) horm.Client {
+	return horm.NewClient(name, append(opts, horm.WithInterceptor(p.Intercept))...)
+}
+
+var _ horm.Interceptor = (&Replayer{}).Intercept
+
+// Intercept 拦截请求并返回匹配的录制记录，不会调用 next。
+func (p *Replayer) Intercept(ctx context.Context, head *cp.RequestHeader,
+	reqBody []byte, next horm.Invoker) (*cp.ResponseHeader, []byte, error) {
+	r := p.match(head.QueryMode, reqBody)
+	if r == nil {
+		return nil, nil, errs.Newf(errs.ErrClientRoute,
+			"replay: no recorded response matched request_id %d, request=%s", head.RequestId, string(reqBody))
+	}
+
+	if r.Response.Err != nil && r.Response.Err.Code != 0 {
+		return nil, nil, r.Response.Err.ToError()
+	}
+
+	respHeader := &cp.ResponseHeader{
+		Version:   r.Response.Version,
+		QueryMode: head.QueryMode,
+		RequestId: head.RequestId,
+		IsNil:     r.Response.IsNil,
+		RspErrs:   r.Response.RspErrs,
+		RspNils:   r.Response.RspNils,
+	}
+
+	return respHeader, r.responseBody(), nil
+}
+
+func (p *Replayer) match(queryMode uint32, reqBody []byte) *Record {
+	key := matchKey(queryMode, reqBody)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	records := p.records[key]
+	if len(records) == 0 {
+		return nil
+	}
+
+	i := p.cursors[key]
+	if i < len(records)-1 {
+		p.cursors[key] = i + 1
+	}
+
+	return records[i]
+}
+
+// matchKey 查询模式 + 按 key 排序后的请求体，保证 map 顺序不同的相同请求可以匹配
+func matchKey(queryMode uint32, reqBody []byte) string {
+	var units interface{}
+	if err := json.Api.Unmarshal(reqBody, &units); err == nil {
+		if buf, err := json.SortApi.Marshal(units); err == nil {
+			reqBody = buf
+		}
+	}
+
+	return strconv.FormatUint(uint64(queryMode), 10) + ":" + string(reqBody)
+}
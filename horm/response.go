@@ -0,0 +1,46 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"time"
+
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/go-horm/horm/client"
+)
+
+// ResponseInfo 请求返回信息，用于排查超时等问题
+type ResponseInfo struct {
+	RequestID uint64        // request_id
+	TraceID   string        // trace_id
+	Timeout   time.Duration // 最后一次请求的超时时间
+	Attempts  int           // 请求次数，包括重试
+	Timing    client.Timing // 最后一次请求的各阶段耗时
+}
+
+func (r *ResponseInfo) set(attempt int, head *proto.RequestHeader, reqParam *client.ReqParam) {
+	if r == nil {
+		return
+	}
+
+	r.RequestID = head.RequestId
+	r.TraceID = head.TraceId
+	r.Timeout = time.Duration(head.Timeout) * time.Millisecond
+	r.Attempts = attempt
+
+	if reqParam.Timing != nil {
+		r.Timing = *reqParam.Timing
+	}
+}
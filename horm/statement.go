@@ -87,7 +87,7 @@ func (s *Query) Where(where Where) *Query {
 	return s
 }
 
-// Insert （批量）插入数据，参数可以是 struct / []struct / Map / []Map
+// Insert （批量）插入数据，参数可以是 struct / []struct / Map / []Map，值不能是 Incr、Expr、Now 等更新表达式
 func (s *Query) Insert(data interface{}) *Query {
 	s.Op("insert")
 
@@ -104,7 +104,7 @@ func (s *Query) Insert(data interface{}) *Query {
 	return s.setMap(mapData)
 }
 
-// Replace （批量）替换数据，参数可以是 struct / []struct / Map / []Map
+// Replace （批量）替换数据，参数可以是 struct / []struct / Map / []Map，值不能是更新表达式
 func (s *Query) Replace(data interface{}) *Query {
 	s.Op("replace")
 
@@ -122,7 +122,7 @@ func (s *Query) Replace(data interface{}) *Query {
 
 }
 
-// Update 更新数据，参数可以是 struct / Map
+// Update 更新数据，参数可以是 struct / Map，值可以是 Incr、Expr、Now 等更新表达式
 func (s *Query) Update(data interface{}, where ...Where) *Query {
 	s.Op("update")
 
@@ -151,6 +151,7 @@ func (s *Query) Update(data interface{}, where ...Where) *Query {
 	}
 
 	s.setDataType(s.Unit.Data)
+	s.encodeExprs()
 
 	return s
 }
@@ -398,6 +399,11 @@ func (s *Query) setMap(data interface{}) *Query {
 		return s
 	}
 
+	s.checkNoExprs(s.Unit.Data)
+	for _, data := range s.Unit.Datas {
+		s.checkNoExprs(data)
+	}
+
 	if len(s.Unit.Datas) > 0 {
 		s.setDataType(s.Unit.Datas[0])
 	} else {
@@ -0,0 +1,245 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 默认直方图分桶（秒）
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// MemRegistry 内存指标注册中心，支持以 prometheus 文本格式输出
+type MemRegistry struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+type family struct {
+	typ    string
+	series map[string]*series
+}
+
+type series struct {
+	labels string
+	value  float64  // counter、gauge 值
+	counts []uint64 // histogram 各分桶计数
+	sum    float64  // histogram 样本和
+	count  uint64   // histogram 样本数
+}
+
+// NewRegistry 创建内存指标注册中心
+// param: buckets 直方图分桶，不传时使用 DefaultBuckets
+func NewRegistry(buckets ...float64) *MemRegistry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	bs := append([]float64{}, buckets...)
+	sort.Float64s(bs)
+
+	return &MemRegistry{
+		buckets:  bs,
+		families: map[string]*family{},
+	}
+}
+
+// AddCounter implements Registry.AddCounter.
+func (r *MemRegistry) AddCounter(name string, labels Labels, v float64) {
+	r.mu.Lock()
+	r.getSeries(name, typeCounter, labels).value += v
+	r.mu.Unlock()
+}
+
+// SetGauge implements Registry.SetGauge.
+func (r *MemRegistry) SetGauge(name string, labels Labels, v float64) {
+	r.mu.Lock()
+	r.getSeries(name, typeGauge, labels).value = v
+	r.mu.Unlock()
+}
+
+// ObserveHistogram implements Registry.ObserveHistogram.
+func (r *MemRegistry) ObserveHistogram(name string, labels Labels, v float64) {
+	r.mu.Lock()
+	s := r.getSeries(name, typeHistogram, labels)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(r.buckets))
+	}
+
+	for i, b := range r.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+
+	s.sum += v
+	s.count++
+	r.mu.Unlock()
+}
+
+func (r *MemRegistry) getSeries(name, typ string, labels Labels) *series {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{typ: typ, series: map[string]*series{}}
+		r.families[name] = f
+	}
+
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// WriteText 以 prometheus 文本格式输出所有指标
+func (r *MemRegistry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		if h, ok := help[name]; ok {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, h)
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, f.typ)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			r.writeSeries(bw, name, f.typ, f.series[key])
+		}
+	}
+	r.mu.Unlock()
+
+	return bw.Flush()
+}
+
+func (r *MemRegistry) writeSeries(w io.Writer, name, typ string, s *series) {
+	if typ != typeHistogram {
+		fmt.Fprintf(w, "%s%s %s\n", name, wrapLabels(s.labels), formatFloat(s.value))
+		return
+	}
+
+	for i, b := range r.buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, wrapLabels(joinLabel(s.labels, "le", formatFloat(b))), s.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, wrapLabels(joinLabel(s.labels, "le", "+Inf")), s.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, wrapLabels(s.labels), formatFloat(s.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, wrapLabels(s.labels), s.count)
+}
+
+// Handler 以 prometheus 文本格式暴露默认注册中心的指标，默认注册中心被替换为非 MemRegistry 时返回 501。
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		Collect()
+
+		r, ok := GetRegistry().(*MemRegistry)
+		if !ok {
+			http.Error(w, "stats registry does not support text format", http.StatusNotImplemented)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// formatLabels 按 key 排序格式化标签，作为 series 唯一标识
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabel(labels[k]))
+		sb.WriteByte('"')
+	}
+
+	return sb.String()
+}
+
+func joinLabel(labels, k, v string) string {
+	label := k + `="` + v + `"`
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMemRegistryWriteText(t *testing.T) {
+	r := NewRegistry(0.1, 0.01, 1)
+
+	labels := Labels{"caller": "app1", "name": "student", "op": "find"}
+	r.AddCounter(RequestTotal, Labels{"caller": "app1", "name": "student", "op": "find", "code": "0"}, 1)
+	r.AddCounter(RequestTotal, Labels{"caller": "app1", "name": "student", "op": "find", "code": "0"}, 2)
+	r.AddCounter(RequestTotal, Labels{"caller": "app1", "name": "student", "op": "find", "code": "102"}, 1)
+	r.AddCounter(PoolWaitCount, Labels{"network": "tcp", "address": "127.0.0.1:8180"}, 3)
+	r.AddCounter(PoolWaitSeconds, Labels{"network": "tcp", "address": "127.0.0.1:8180"}, 0.25)
+	r.SetGauge(PoolActiveConns, Labels{"network": "tcp", "address": "127.0.0.1:8180"}, 5)
+	r.SetGauge(PoolActiveConns, Labels{"network": "tcp", "address": "127.0.0.1:8180"}, 4)
+	r.SetGauge(NodeHealthy, Labels{"node": "a\"b\\c\nd"}, 1)
+	r.SetGauge("custom_gauge", nil, math.Inf(1))
+	r.ObserveHistogram(RequestDuration, labels, 0.005)
+	r.ObserveHistogram(RequestDuration, labels, 0.05)
+	r.ObserveHistogram(RequestDuration, labels, 2)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("write text error: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "registry.golden")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("update golden error: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden error: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("text output mismatch, got:\n%s\nwant:\n%s", buf.Bytes(), want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	old := GetRegistry()
+	defer SetRegistry(old)
+
+	r := NewRegistry()
+	SetRegistry(r)
+	RegisterCollector(func(r Registry) { r.SetGauge(PoolIdleConns, Labels{"address": "a"}, 2) })
+
+	AddCounter(CoalescedTotal, Labels{"client": "c1"}, 1)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE horm_client_coalesced_requests_total counter\nhorm_client_coalesced_requests_total{client=\"c1\"} 1\n",
+		"# TYPE horm_client_pool_idle_connections gauge\nhorm_client_pool_idle_connections{address=\"a\"} 2\n",
+	} {
+		if !bytes.Contains([]byte(body), []byte(want)) {
+			t.Fatalf("handler output missing %q in:\n%s", want, body)
+		}
+	}
+
+	SetRegistry(customRegistry{})
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 501 {
+		t.Fatalf("handler status %d with custom registry, want 501", rec.Code)
+	}
+}
+
+type customRegistry struct{}
+
+func (customRegistry) AddCounter(string, Labels, float64)       {}
+func (customRegistry) SetGauge(string, Labels, float64)         {}
+func (customRegistry) ObserveHistogram(string, Labels, float64) {}
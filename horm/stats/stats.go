@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stats 客户端监控指标，默认使用内存注册中心，可通过 SetRegistry 替换为业务自有的监控系统，
+// 内存注册中心可通过 Handler 以 prometheus 文本格式暴露。
+package stats
+
+import (
+	"sync"
+)
+
+// 客户端指标
+const (
+	RequestTotal        = "horm_client_requests_total"           // 执行单元请求数
+	RequestDuration     = "horm_client_request_duration_seconds" // 执行单元请求耗时
+	DecodeFailTotal     = "horm_client_decode_failures_total"    // 返回解码失败数
+	SentBytesTotal      = "horm_client_sent_bytes_total"         // 发送字节数
+	ReceivedBytesTotal  = "horm_client_received_bytes_total"     // 接收字节数
+	NodeRequestTotal    = "horm_client_node_requests_total"      // 节点请求数
+	NodeHealthy         = "horm_client_node_healthy"             // 节点是否健康，1 健康 0 不健康
+	PoolActiveConns     = "horm_client_pool_active_connections"  // 连接池活跃连接数
+	PoolIdleConns       = "horm_client_pool_idle_connections"    // 连接池空闲连接数
+	PoolWaitingRequests = "horm_client_pool_waiting_requests"    // 连接池等待获取连接的请求数
+	PoolWaitCount       = "horm_client_pool_waits_total"         // 连接池等待获取连接次数
+	PoolWaitSeconds     = "horm_client_pool_wait_seconds_total"  // 连接池等待获取连接耗时
+	CoalescedTotal      = "horm_client_coalesced_requests_total" // 被合并的只读请求数
+)
+
+var help = map[string]string{
+	RequestTotal:        "Total number of horm query units by caller, name, op and result code.",
+	RequestDuration:     "Latency of horm query units in seconds by caller, name and op.",
+	DecodeFailTotal:     "Total number of horm result decode failures by caller, name and op.",
+	SentBytesTotal:      "Total bytes sent to horm server by node.",
+	ReceivedBytesTotal:  "Total bytes received from horm server by node.",
+	NodeRequestTotal:    "Total number of requests to horm server node by node and result.",
+	NodeHealthy:         "Whether the last request to horm server node succeeded at network level.",
+	PoolActiveConns:     "Number of active connections in pool by address.",
+	PoolIdleConns:       "Number of idle connections in pool by address.",
+	PoolWaitingRequests: "Number of requests waiting for a connection by address.",
+	PoolWaitCount:       "Total number of waits for a connection by address.",
+	PoolWaitSeconds:     "Total seconds spent waiting for a connection by address.",
+	CoalescedTotal:      "Total number of read-only requests coalesced into an in-flight identical request by client.",
+}
+
+// Labels 指标标签
+type Labels map[string]string
+
+// Registry 指标注册中心
+type Registry interface {
+	AddCounter(name string, labels Labels, v float64)       // 累加计数器
+	SetGauge(name string, labels Labels, v float64)         // 设置仪表盘值
+	ObserveHistogram(name string, labels Labels, v float64) // 记录直方图样本
+}
+
+// Collector 指标采集函数，在指标暴露前调用，用于采集连接池等状态类指标
+type Collector func(r Registry)
+
+var (
+	lock                = new(sync.RWMutex)
+	registry   Registry = NewRegistry()
+	collectors []Collector
+)
+
+// SetRegistry 设置指标注册中心
+func SetRegistry(r Registry) {
+	lock.Lock()
+	registry = r
+	lock.Unlock()
+}
+
+// GetRegistry 获取指标注册中心
+func GetRegistry() Registry {
+	lock.RLock()
+	r := registry
+	lock.RUnlock()
+	return r
+}
+
+// RegisterCollector 注册指标采集函数
+func RegisterCollector(c Collector) {
+	lock.Lock()
+	collectors = append(collectors, c)
+	lock.Unlock()
+}
+
+// Collect 调用所有指标采集函数，将状态类指标写入注册中心
+func Collect() {
+	lock.RLock()
+	r, cs := registry, collectors
+	lock.RUnlock()
+
+	for _, c := range cs {
+		c(r)
+	}
+}
+
+// AddCounter 累加计数器
+func AddCounter(name string, labels Labels, v float64) {
+	GetRegistry().AddCounter(name, labels, v)
+}
+
+// SetGauge 设置仪表盘值
+func SetGauge(name string, labels Labels, v float64) {
+	GetRegistry().SetGauge(name, labels, v)
+}
+
+// ObserveHistogram 记录直方图样本
+func ObserveHistogram(name string, labels Labels, v float64) {
+	GetRegistry().ObserveHistogram(name, labels, v)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/horm-database/go-horm/horm"
+
+// startSpan 开启 Exec/PExec/CompExec span，TracerProvider 未设置时使用 otel 全局 TracerProvider
+func startSpan(ctx context.Context, opts *Options, name string, q *Query) (context.Context, trace.Span) {
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	var names, ops, shards []string
+	walkQuery(q.GetHead(), func(s *Query) {
+		names = append(names, s.Unit.Name)
+		ops = append(ops, s.Unit.Op)
+		shards = append(shards, s.Unit.Shard...)
+	})
+
+	attrs := []attribute.KeyValue{
+		attribute.StringSlice("horm.unit.names", names),
+		attribute.StringSlice("horm.unit.ops", ops),
+		attribute.Int("horm.workspace_id", opts.WorkspaceID),
+		attribute.String("horm.caller", opts.Name),
+	}
+
+	if len(shards) > 0 {
+		attrs = append(attrs, attribute.StringSlice("horm.unit.shards", shards))
+	}
+
+	return tp.Tracer(tracerName).Start(ctx, "horm."+name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan 结束 span，并记录错误
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext 将 context 中的 W3C trace-context（traceparent、tracestate）注入到 metadata，
+// 仅在开启 MetadataHeader 时随 metadata 透传到服务端
+func injectTraceContext(ctx context.Context, opts *Options) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	if opts.Metadata == nil {
+		opts.Metadata = map[string]string{}
+	}
+
+	propagation.TraceContext{}.Inject(ctx, propagation.MapCarrier(opts.Metadata))
+}
+
+// walkQuery 遍历所有执行单元，包括并行查询、子查询、事务语句
+func walkQuery(q *Query, fn func(s *Query)) {
+	for ; q != nil; q = q.next {
+		fn(q)
+		walkQuery(q.sub, fn)
+		walkQuery(q.trans, fn)
+	}
+}
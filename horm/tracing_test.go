@@ -0,0 +1,174 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	tests := []struct {
+		name    string
+		exec    func(c Client) error
+		span    string
+		units   []string
+		ops     []string
+		shards  []string
+		resp    error
+		wantErr bool
+	}{
+		{
+			name: "exec",
+			exec: func(c Client) error {
+				_, err := c.Exec(context.Background(), NewQuery("student").Shard("student_1").Find(Where{"id": 1}))
+				return err
+			},
+			span:   "horm.Exec",
+			units:  []string{"student"},
+			ops:    []string{"find"},
+			shards: []string{"student_1"},
+		},
+		{
+			name: "pexec",
+			exec: func(c Client) error {
+				q := NewQuery("student").Find(Where{"id": 1})
+				q.Next("course").FindAll()
+				return c.PExec(context.Background(), q)
+			},
+			span:  "horm.PExec",
+			units: []string{"student", "course"},
+			ops:   []string{"find", "find_all"},
+		},
+		{
+			name: "error",
+			exec: func(c Client) error {
+				_, err := c.Exec(context.Background(), NewQuery("student").Delete(Where{"id": 1}))
+				return err
+			},
+			span:    "horm.Exec",
+			units:   []string{"student"},
+			ops:     []string{"delete"},
+			resp:    &errs.Error{Type: errs.ETypeSystem, Code: 1062, Msg: "duplicate entry"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			var bak string
+			interceptor := func(ctx context.Context, head *proto.RequestHeader,
+				reqBody []byte, next Invoker) (*proto.ResponseHeader, []byte, error) {
+				bak = head.Bak
+				if tt.resp != nil {
+					return nil, nil, tt.resp
+				}
+				return &proto.ResponseHeader{RequestId: head.RequestId, QueryMode: head.QueryMode}, []byte(`{}`), nil
+			}
+
+			c := NewClient("ws_test.app1.server1.service1", WithTracerProvider(tp),
+				WithInterceptor(interceptor), WithMetadataHeader(), WithWorkspaceID(31))
+
+			if err := tt.exec(c); (err != nil) != tt.wantErr {
+				t.Fatalf("exec error %v, want error %v", err, tt.wantErr)
+			}
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("ended spans %d, want 1", len(spans))
+			}
+
+			span := spans[0]
+			if span.Name() != tt.span {
+				t.Fatalf("span name %s, want %s", span.Name(), tt.span)
+			}
+
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+
+			if got := attrs["horm.unit.names"].AsStringSlice(); strings.Join(got, ",") != strings.Join(tt.units, ",") {
+				t.Fatalf("unit names %v, want %v", got, tt.units)
+			}
+
+			if got := attrs["horm.unit.ops"].AsStringSlice(); strings.Join(got, ",") != strings.Join(tt.ops, ",") {
+				t.Fatalf("unit ops %v, want %v", got, tt.ops)
+			}
+
+			if got, ok := attrs["horm.unit.shards"]; ok != (len(tt.shards) > 0) ||
+				ok && strings.Join(got.AsStringSlice(), ",") != strings.Join(tt.shards, ",") {
+				t.Fatalf("unit shards %v, want %v", got.Emit(), tt.shards)
+			}
+
+			if attrs["horm.workspace_id"].AsInt64() != 31 ||
+				attrs["horm.caller"].AsString() != "ws_test.app1.server1.service1" {
+				t.Fatalf("span attributes %v", span.Attributes())
+			}
+
+			if tt.wantErr {
+				if span.Status().Code != codes.Error || len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+					t.Fatalf("span status %v events %v", span.Status(), span.Events())
+				}
+			} else if span.Status().Code == codes.Error {
+				t.Fatalf("span status %v", span.Status())
+			}
+
+			// traceparent 为 horm 调用 span 的 trace-context
+			metadata := map[string]string{}
+			if err := json.Api.Unmarshal([]byte(bak), &metadata); err != nil {
+				t.Fatalf("decode head bak %s error %v", bak, err)
+			}
+
+			sc := span.SpanContext()
+			want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+			if metadata["traceparent"] != want {
+				t.Fatalf("traceparent %s, want %s", metadata["traceparent"], want)
+			}
+		})
+	}
+}
+
+func TestTracingNoMetadataHeader(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+
+	var bak = "unset"
+	interceptor := func(ctx context.Context, head *proto.RequestHeader,
+		reqBody []byte, next Invoker) (*proto.ResponseHeader, []byte, error) {
+		bak = head.Bak
+		return okHandler(head, reqBody)
+	}
+
+	c := NewClient("ws_test.app1.server1.service1", WithTracerProvider(tp), WithInterceptor(interceptor))
+	if _, err := c.Exec(context.Background(), NewQuery("student").Find(Where{"id": 1})); err != nil {
+		t.Fatalf("exec error %v", err)
+	}
+
+	if bak != "" {
+		t.Fatalf("trace context sent without metadata header: %s", bak)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"time"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+)
+
+// 事务隔离级别
+const (
+	IsolationReadUncommitted = "READ UNCOMMITTED"
+	IsolationReadCommitted   = "READ COMMITTED"
+	IsolationRepeatableRead  = "REPEATABLE READ"
+	IsolationSerializable    = "SERIALIZABLE"
+)
+
+// 事务、行锁参数，由统一接入服务转换为对应数据库的语法
+const (
+	paramIsolation   = "isolation"    // 事务隔离级别
+	paramReadOnly    = "read_only"    // 只读事务
+	paramLockTimeout = "lock_timeout" // 锁等待超时（毫秒）
+	paramLock        = "lock"         // 行锁 update、share
+	paramLockWait    = "lock_wait"    // 行锁等待方式 skip_locked、nowait
+
+	lockUpdate     = "update"
+	lockShare      = "share"
+	lockSkipLocked = "skip_locked"
+	lockNoWait     = "nowait"
+)
+
+// Isolation 设置事务隔离级别，可选 IsolationReadUncommitted、IsolationReadCommitted、IsolationRepeatableRead、IsolationSerializable
+func (s *Query) Isolation(level string) *Query {
+	switch level {
+	case IsolationReadUncommitted, IsolationReadCommitted, IsolationRepeatableRead, IsolationSerializable:
+	default:
+		s.Error = errs.Newf(errs.ErrReqParamInvalid, "invalid transaction isolation level %q", level)
+		return s
+	}
+
+	return s.transParam(paramIsolation, level)
+}
+
+// ReadOnly 设置为只读事务
+func (s *Query) ReadOnly() *Query {
+	return s.transParam(paramReadOnly, true)
+}
+
+// LockTimeout 设置事务内锁等待超时时间，mysql 为 innodb_lock_wait_timeout（秒，向上取整），postgresql 为 lock_timeout
+func (s *Query) LockTimeout(timeout time.Duration) *Query {
+	if timeout <= 0 {
+		s.Error = errs.New(errs.ErrReqParamInvalid, "transaction lock timeout must be greater than zero")
+		return s
+	}
+
+	return s.transParam(paramLockTimeout, timeout.Milliseconds())
+}
+
+// ForUpdate 查询加排他锁 SELECT ... FOR UPDATE，仅支持 mysql、postgresql，需在事务中的 Find、FindAll 语句使用
+func (s *Query) ForUpdate() *Query {
+	return s.lockParam(paramLock, lockUpdate)
+}
+
+// ForShare 查询加共享锁，mysql 为 LOCK IN SHARE MODE / FOR SHARE，postgresql 为 FOR SHARE，
+// 仅支持 mysql、postgresql，需在事务中的 Find、FindAll 语句使用
+func (s *Query) ForShare() *Query {
+	return s.lockParam(paramLock, lockShare)
+}
+
+// SkipLocked 跳过已被锁定的行，需与 ForUpdate、ForShare 一起使用
+func (s *Query) SkipLocked() *Query {
+	return s.lockParam(paramLockWait, lockSkipLocked)
+}
+
+// NoWait 行已被锁定时不等待直接返回错误，需与 ForUpdate、ForShare 一起使用
+func (s *Query) NoWait() *Query {
+	return s.lockParam(paramLockWait, lockNoWait)
+}
+
+// transParam 设置事务参数，仅对 NewTransaction 创建的事务语句有效
+func (s *Query) transParam(key string, value interface{}) *Query {
+	if s.Unit.Op != consts.OpTransaction {
+		s.Error = errs.Newf(errs.ErrReqParamInvalid, "%s is only valid for transaction, got op %s", key, s.Unit.Op)
+		return s
+	}
+
+	return s.SetParam(key, value)
+}
+
+// lockParam 设置行锁参数，仅支持 mysql、postgresql，语句需要在事务中，在执行时由 checkLock 校验
+func (s *Query) lockParam(key, value string) *Query {
+	typ, err := s.dbType()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	if typ != dbTypeMySQL && typ != dbTypePostgreSQL {
+		s.Error = errs.Newf(errs.ErrReqParamInvalid, "row lock %s is not supported by %s", value, typ)
+		return s
+	}
+
+	return s.SetParam(key, value)
+}
+
+// checkLock 校验行锁参数，行锁只能用于事务中的查询语句，SkipLocked、NoWait 需要与 ForUpdate、ForShare 一起使用
+func checkLock(q *Query, inTrans bool) error {
+	lock, wait := q.Unit.Params[paramLock], q.Unit.Params[paramLockWait]
+	if lock == nil && wait == nil {
+		return nil
+	}
+
+	if lock == nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "unit %s lock wait %v requires ForUpdate or ForShare", q.Unit.Name, wait)
+	}
+
+	if !inTrans {
+		return errs.Newf(errs.ErrReqParamInvalid,
+			"unit %s row lock %v is only valid in transaction, use NewTransaction", q.Unit.Name, lock)
+	}
+
+	if q.Unit.Op != consts.OpFind && q.Unit.Op != consts.OpFindAll {
+		return errs.Newf(errs.ErrReqParamInvalid, "unit %s row lock %v is only valid for find, got op %s",
+			q.Unit.Name, lock, q.Unit.Op)
+	}
+
+	return nil
+}
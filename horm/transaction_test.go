@@ -0,0 +1,101 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"testing"
+
+	"github.com/horm-database/common/errs"
+)
+
+func TestRowLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func() *Query
+		code  int
+	}{
+		{"mysql in transaction", func() *Query {
+			return NewTransaction("mysql_test", NewQuery("mysql_test").Find(Where{"id": 1}).ForUpdate())
+		}, 0},
+		{"postgresql share skip locked", func() *Query {
+			return NewTransaction("postgres_test", NewQuery("postgres_test").FindAll().ForShare().SkipLocked())
+		}, 0},
+		{"lock wait before lock", func() *Query {
+			return NewTransaction("mysql_test", NewQuery("mysql_test").FindAll().NoWait().ForUpdate())
+		}, 0},
+		{"second statement of transaction", func() *Query {
+			trans := NewQuery("mysql_test").Find(Where{"id": 1}).ForUpdate()
+			trans.Next("mysql_test").Update(Map{"age": 1}, Where{"id": 1})
+			return NewTransaction("mysql_test", trans)
+		}, 0},
+		{"not in transaction", func() *Query {
+			return NewQuery("mysql_test").Find(Where{"id": 1}).ForUpdate()
+		}, errs.ErrReqParamInvalid},
+		{"parallel not in transaction", func() *Query {
+			q := NewQuery("mysql_test").Find(Where{"id": 1})
+			q.Next("postgres_test").Find(Where{"id": 1}).ForShare()
+			return q
+		}, errs.ErrReqParamInvalid},
+		{"lock wait without lock", func() *Query {
+			return NewTransaction("mysql_test", NewQuery("mysql_test").FindAll().SkipLocked())
+		}, errs.ErrReqParamInvalid},
+		{"lock update statement", func() *Query {
+			return NewTransaction("mysql_test", NewQuery("mysql_test").Update(Map{"age": 1}).ForUpdate())
+		}, errs.ErrReqParamInvalid},
+		{"elastic", func() *Query {
+			return NewTransaction("es_test", NewQuery("es_test").FindAll().ForUpdate())
+		}, errs.ErrReqParamInvalid},
+		{"clickhouse", func() *Query {
+			return NewTransaction("clickhouse_test", NewQuery("clickhouse_test").FindAll().NoWait())
+		}, errs.ErrReqParamInvalid},
+		{"unknown db type", func() *Query {
+			return NewTransaction("student", NewQuery("student").FindAll().ForUpdate())
+		}, errs.ErrDBConfigNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := createUnits(tt.query())
+			if errs.Code(err) != tt.code {
+				t.Fatalf("create units error %v, want code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestTransParam(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		code  int
+	}{
+		{"isolation", NewTransaction("mysql_test", NewQuery("mysql_test").FindAll()).Isolation(IsolationSerializable), 0},
+		{"invalid isolation", NewTransaction("mysql_test", NewQuery("mysql_test").FindAll()).Isolation("NONE"),
+			errs.ErrReqParamInvalid},
+		{"isolation not transaction", NewQuery("mysql_test").FindAll().Isolation(IsolationReadCommitted),
+			errs.ErrReqParamInvalid},
+		{"read only not transaction", NewQuery("mysql_test").FindAll().ReadOnly(), errs.ErrReqParamInvalid},
+		{"lock timeout", NewTransaction("mysql_test", NewQuery("mysql_test").FindAll()).LockTimeout(0),
+			errs.ErrReqParamInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs.Code(tt.query.Error) != tt.code {
+				t.Fatalf("error %v, want code %d", tt.query.Error, tt.code)
+			}
+		})
+	}
+}
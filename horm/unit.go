@@ -18,10 +18,12 @@ import (
 	"github.com/horm-database/common/proto"
 )
 
+const unitTimeoutKey = "timeout" // 执行单元超时时间在 extend 中的 key
+
 func createUnits(q *Query) ([]*proto.Unit, error) {
 	units := make([]*proto.Unit, 0)
 
-	err := addUnit(&units, q)
+	err := addUnit(&units, q, false)
 	if err != nil {
 		return nil, err
 	}
@@ -29,20 +31,33 @@ func createUnits(q *Query) ([]*proto.Unit, error) {
 	return units, nil
 }
 
-func addUnit(units *[]*proto.Unit, q *Query) error {
+// addUnit 添加执行单元及其子查询、事务语句、并行语句，inTrans 表示 q 是否为事务中的语句
+func addUnit(units *[]*proto.Unit, q *Query, inTrans bool) error {
 	if q.Error != nil {
 		return q.Error
 	}
 
+	if err := checkLock(q, inTrans); err != nil {
+		return err
+	}
+
+	if err := checkReturning(q); err != nil {
+		return err
+	}
+
 	if q.Unit.Size < 0 {
 		q.Unit.Size = 0
 	}
 
+	if q.Timeout > 0 { // 执行单元超时时间，单位毫秒
+		q.Extend(unitTimeoutKey, q.Timeout.Milliseconds())
+	}
+
 	*units = append(*units, q.Unit)
 
 	if q.sub != nil {
 		q.Unit.Sub = make([]*proto.Unit, 0)
-		err := addUnit(&q.Unit.Sub, q.sub)
+		err := addUnit(&q.Unit.Sub, q.sub, inTrans)
 		if err != nil {
 			return err
 		}
@@ -50,14 +65,14 @@ func addUnit(units *[]*proto.Unit, q *Query) error {
 
 	if q.trans != nil {
 		q.Unit.Trans = make([]*proto.Unit, 0)
-		err := addUnit(&q.Unit.Trans, q.trans)
+		err := addUnit(&q.Unit.Trans, q.trans, true)
 		if err != nil {
 			return err
 		}
 	}
 
 	if q.next != nil {
-		err := addUnit(units, q.next)
+		err := addUnit(units, q.next, inTrans)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,198 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/types"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/go-horm/horm/codec"
+)
+
+// 插入冲突处理参数，由统一接入服务转换为对应数据库的语法
+const (
+	paramOnConflict      = "on_conflict"      // 冲突处理方式 update、ignore
+	paramConflictColumns = "conflict_columns" // 冲突列，postgresql ON CONFLICT (columns)
+	paramUpdateColumns   = "update_columns"   // 冲突时更新的列
+	paramDocAsUpsert     = "doc_as_upsert"    // elastic 文档不存在时插入
+	paramReturning       = "returning"        // postgresql RETURNING 返回列
+
+	conflictUpdate = "update"
+	conflictIgnore = "ignore"
+)
+
+// 数据库类型，对应配置文件 db 的 type
+const (
+	dbTypeMySQL      = "mysql"
+	dbTypePostgreSQL = "postgresql"
+	dbTypeClickHouse = "clickhouse"
+	dbTypeElastic    = "elastic"
+	dbTypeRedis      = "redis"
+)
+
+// Upsert 插入数据，主键或唯一键冲突时更新，参数可以是 struct / []struct / Map / []Map，
+// mysql 为 ON DUPLICATE KEY UPDATE，postgresql 为 ON CONFLICT (conflictColumns) DO UPDATE，
+// elastic 为 doc_as_upsert 更新（需要通过 ID 指定文档，仅支持单条数据），
+// clickhouse 没有冲突更新语法，为普通插入，由 ReplacingMergeTree 表引擎按排序键合并去重。
+// param: conflictColumns 冲突列，postgresql 必填
+// param: updateColumns 冲突时更新的列，不传时为数据中除冲突列外的所有列，结构体数据会按 omitupdateempty 等标签忽略空值列，
+// 批量数据各条的列不一致时需要显式指定
+func (s *Query) Upsert(data interface{}, conflictColumns []string, updateColumns ...string) *Query {
+	typ, err := s.dbType()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	switch typ {
+	case dbTypePostgreSQL:
+		if len(conflictColumns) == 0 {
+			s.Error = errs.New(errs.ErrReqParamInvalid, "postgresql upsert requires conflict columns")
+			return s
+		}
+	case dbTypeElastic:
+		s.Update(data)
+		s.SetParam(paramDocAsUpsert, true)
+		return s
+	case dbTypeClickHouse:
+		return s.Insert(data)
+	}
+
+	s.Insert(data)
+	if s.Error != nil {
+		return s
+	}
+
+	if len(updateColumns) == 0 {
+		updateColumns = s.upsertColumns(data, conflictColumns)
+		if s.Error != nil {
+			return s
+		}
+	}
+
+	s.SetParam(paramOnConflict, conflictUpdate)
+	s.SetParam(paramUpdateColumns, updateColumns)
+	if len(conflictColumns) > 0 {
+		s.SetParam(paramConflictColumns, conflictColumns)
+	}
+
+	return s
+}
+
+// InsertIgnore 插入数据，主键或唯一键冲突时忽略，参数可以是 struct / []struct / Map / []Map，
+// mysql 为 INSERT IGNORE，postgresql 为 ON CONFLICT (conflictColumns) DO NOTHING，clickhouse 为普通插入。
+// param: conflictColumns 冲突列，仅 postgresql 使用，不传时为 ON CONFLICT DO NOTHING
+func (s *Query) InsertIgnore(data interface{}, conflictColumns ...string) *Query {
+	typ, err := s.dbType()
+	if err != nil {
+		s.Error = err
+		return s
+	}
+
+	s.Insert(data)
+
+	if s.Error != nil || typ == dbTypeClickHouse {
+		return s
+	}
+
+	s.SetParam(paramOnConflict, conflictIgnore)
+	if len(conflictColumns) > 0 {
+		s.SetParam(paramConflictColumns, conflictColumns)
+	}
+
+	return s
+}
+
+// upsertColumns 冲突时更新的列，按更新操作编码数据（忽略 omitupdateempty 的空值列），并排除冲突列，
+// 批量数据各条编码后的列不一致时返回错误，以免把部分数据忽略的空值列更新为插入值，此时需要显式指定 updateColumns
+func (s *Query) upsertColumns(data interface{}, conflictColumns []string) []string {
+	conflict := make(map[string]bool, len(conflictColumns))
+	for _, column := range conflictColumns {
+		conflict[column] = true
+	}
+
+	var columns []string
+	for i, data := range upsertRows(data) {
+		row, err := s.GetCoder().Encode(codec.EncodeTypeUpdateData, data)
+		if err != nil {
+			s.Error = err
+			return nil
+		}
+
+		var m map[string]interface{}
+		switch v := row.(type) {
+		case map[string]interface{}:
+			m = v
+		case Map:
+			m = v
+		case types.Map:
+			m = v
+		default:
+			s.Error = errs.New(errs.ErrReqParamInvalid, "upsert data`s type must be struct/[]struct/map/[]map")
+			return nil
+		}
+
+		rowColumns := make([]string, 0, len(m))
+		for _, column := range sortedKeys(m) {
+			if !conflict[column] {
+				rowColumns = append(rowColumns, column)
+			}
+		}
+
+		if i == 0 {
+			columns = rowColumns
+		} else if strings.Join(rowColumns, ",") != strings.Join(columns, ",") {
+			s.Error = errs.Newf(errs.ErrReqParamInvalid, "upsert data row %d has update columns %v, "+
+				"different from %v of row 0, please specify update columns", i, rowColumns, columns)
+			return nil
+		}
+	}
+
+	return columns
+}
+
+// upsertRows 批量数据的每一条，单条数据时为其本身
+func upsertRows(data interface{}) []interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(data))
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{data}
+	}
+
+	rows := make([]interface{}, rv.Len())
+	for i := range rows {
+		rows[i] = rv.Index(i).Interface()
+	}
+	return rows
+}
+
+// dbType 执行单元对应的数据库类型，取配置文件 orm.yaml 中 name 与执行单元名（不含别名）相同的 db 配置的 type，
+// Count、Sum、Upsert、KeysetExec 等需要按数据库类型生成请求的方法依赖该配置，未配置或类型未知时返回错误
+func (s *Query) dbType() (string, error) {
+	name, _ := util.Alias(s.Unit.Name)
+	dbCfg, err := unitDBConfig(s)
+	if err != nil {
+		return "", errs.Newf(errs.ErrDBConfigNotFound, "not find db config %s in orm.yaml to get database type", name)
+	}
+
+	switch dbCfg.Type {
+	case dbTypeMySQL, dbTypePostgreSQL, dbTypeClickHouse, dbTypeElastic, dbTypeRedis:
+		return dbCfg.Type, nil
+	}
+
+	return "", errs.Newf(errs.ErrDBConfigNotFound, "db config %s has unknown type %q", name, dbCfg.Type)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package horm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+)
+
+func TestUpsertColumns(t *testing.T) {
+	rows := []map[string]interface{}{{"id": 1, "name": "a", "age": 10}, {"id": 2, "name": "b", "age": 11}}
+	diff := []map[string]interface{}{{"id": 1, "name": "a", "age": 10}, {"id": 2, "name": "b"}}
+
+	tests := []struct {
+		name     string
+		table    string
+		data     interface{}
+		conflict []string
+		update   []string
+		params   map[string]interface{}
+		code     int
+	}{
+		{"mysql single", "mysql_test", Map{"id": 1, "name": "a"}, nil, nil,
+			map[string]interface{}{paramOnConflict: conflictUpdate, paramUpdateColumns: []string{"id", "name"}}, 0},
+		{"mysql batch", "mysql_test", rows, []string{"id"}, nil, map[string]interface{}{paramOnConflict: conflictUpdate,
+			paramUpdateColumns: []string{"age", "name"}, paramConflictColumns: []string{"id"}}, 0},
+		{"mysql batch different columns", "mysql_test", diff, []string{"id"}, nil, nil, errs.ErrReqParamInvalid},
+		{"mysql batch different columns specified", "mysql_test", diff, []string{"id"}, []string{"name"},
+			map[string]interface{}{paramOnConflict: conflictUpdate,
+				paramUpdateColumns: []string{"name"}, paramConflictColumns: []string{"id"}}, 0},
+		{"postgresql", "postgres_test", rows, []string{"id"}, nil, map[string]interface{}{paramOnConflict: conflictUpdate,
+			paramUpdateColumns: []string{"age", "name"}, paramConflictColumns: []string{"id"}}, 0},
+		{"postgresql without conflict columns", "postgres_test", rows, nil, nil, nil, errs.ErrReqParamInvalid},
+		{"clickhouse", "clickhouse_test", rows, []string{"id"}, nil, nil, 0},
+		{"elastic", "es_test", Map{"name": "a"}, nil, nil, map[string]interface{}{paramDocAsUpsert: true}, 0},
+		{"unknown db type", "student", rows, []string{"id"}, nil, nil, errs.ErrDBConfigNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery(tt.table).Upsert(tt.data, tt.conflict, tt.update...)
+			if tt.code != 0 {
+				if errs.Code(q.Error) != tt.code {
+					t.Fatalf("upsert error %v, want code %d", q.Error, tt.code)
+				}
+				return
+			}
+
+			if q.Error != nil {
+				t.Fatalf("upsert error: %v", q.Error)
+			}
+
+			if len(q.Unit.Params) != len(tt.params) || (len(tt.params) > 0 && !reflect.DeepEqual(q.Unit.Params, tt.params)) {
+				t.Fatalf("upsert params %v, want %v", q.Unit.Params, tt.params)
+			}
+		})
+	}
+}
+
+func TestInsertIgnore(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		conflict []string
+		params   map[string]interface{}
+	}{
+		{"mysql", "mysql_test", nil, map[string]interface{}{paramOnConflict: conflictIgnore}},
+		{"postgresql any conflict", "postgres_test", nil, map[string]interface{}{paramOnConflict: conflictIgnore}},
+		{"postgresql", "postgres_test", []string{"id"},
+			map[string]interface{}{paramOnConflict: conflictIgnore, paramConflictColumns: []string{"id"}}},
+		{"clickhouse", "clickhouse_test", []string{"id"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery(tt.table).InsertIgnore(Map{"id": 1}, tt.conflict...)
+			if q.Error != nil {
+				t.Fatalf("insert ignore error: %v", q.Error)
+			}
+
+			if len(q.Unit.Params) != len(tt.params) || (len(tt.params) > 0 && !reflect.DeepEqual(q.Unit.Params, tt.params)) {
+				t.Fatalf("insert ignore params %v, want %v", q.Unit.Params, tt.params)
+			}
+		})
+	}
+}